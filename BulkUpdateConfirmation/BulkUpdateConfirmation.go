@@ -1,13 +1,13 @@
 package BulkUpdateConfirmation
 
 import (
+	"errors"
 	"fmt"
 	"smart-cache-cli/RedisCommon"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/redis/go-redis/v9"
 )
 
 func (m Model) Init() tea.Cmd {
@@ -17,6 +17,9 @@ func (m Model) Init() tea.Cmd {
 type BulkConfirmationMessage struct {
 	Message         string
 	ConfirmedUpdate bool
+	Err             error
+	// Reload asks the parent to reload the rules, re-apply its pending edits and confirm again.
+	Reload bool
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -29,12 +32,37 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyEsc.String(), tea.KeyCtrlC.String(), "q":
 			return m, tea.Quit
 		case "y", "Y":
-			RedisCommon.UpdateRules(m.rdb, m.rulesToAdd, m.rulesToUpdate, m.rulesToDelete, m.applicationName)
+			if m.stale != nil {
+				return m, cmd
+			}
+			err := m.store.UpdateRules(m.baseId, m.order)
+			if errors.Is(err, RedisCommon.ErrRulesOutOfSync) {
+				m.stale = err
+				current, getErr := m.store.GetRuleSet()
+				if getErr != nil {
+					m.staleChanges = fmt.Sprintf("could not load the current rules: %v\n", getErr)
+				} else {
+					m.staleChanges = RedisCommon.FormatRuleDiff(RedisCommon.DiffRules(m.baseRules, current.Rules))
+				}
+				return m, cmd
+			}
+			if err != nil {
+				m.parentModel, _ = m.parentModel.Update(BulkConfirmationMessage{
+					ConfirmedUpdate: true,
+					Err:             err,
+				})
+				return m.parentModel, cmd
+			}
 			m.parentModel, _ = m.parentModel.Update(BulkConfirmationMessage{
 				Message:         "Rule Updates Committed to Redis.",
 				ConfirmedUpdate: true,
 			})
 			return m.parentModel, cmd
+		case "r", "R":
+			if m.stale == nil {
+				return m, cmd
+			}
+			return m.parentModel.Update(BulkConfirmationMessage{Reload: true})
 		case "n", "N":
 			m.parentModel.Update(BulkConfirmationMessage{
 				ConfirmedUpdate: false,
@@ -49,25 +77,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) View() string {
 	body := strings.Builder{}
 
-	if len(m.rulesToAdd) > 0 {
-		body.WriteString("====== Rules to Add ======\n")
-		for _, r := range m.rulesToAdd {
-			body.WriteString(r.Formatted() + "\n")
-		}
+	body.WriteString(RedisCommon.FormatRuleUpdates(m.baseRules, m.order))
+	body.WriteString("\nImpact on queries:\n")
+	if m.impactErr != nil {
+		body.WriteString(fmt.Sprintf("Could not preview the impact: %v\n", m.impactErr))
+	} else {
+		body.WriteString(m.impact.Formatted())
 	}
 
-	if len(m.rulesToUpdate) > 0 {
-		body.WriteString("\n\n====== Rules To Update ======\n")
-		for _, r := range m.rulesToUpdate {
-			body.WriteString(fmt.Sprintf("%s\n", r.Formatted()))
-		}
-	}
-
-	if len(m.rulesToDelete) > 0 {
-		body.WriteString("\n\n====== Rules to Delete ======\n")
-		for _, r := range m.rulesToDelete {
-			body.WriteString(fmt.Sprintf("%s\n", r.Formatted()))
-		}
+	if m.stale != nil {
+		body.WriteString(fmt.Sprintf("\n%v\n", m.stale))
+		body.WriteString("The rules were changed since they were loaded:\n")
+		body.WriteString(m.staleChanges)
+		body.WriteString("Reload the rules and re-apply your pending updates? (r to reload, n to cancel)")
+		return body.String()
 	}
 
 	body.WriteString("Do you want to continue? (y/N)")
@@ -75,25 +98,30 @@ func (m Model) View() string {
 }
 
 type Model struct {
-	parentModel     tea.Model
-	inputMode       textinput.Model
-	rulesToAdd      []RedisCommon.Rule
-	rulesToUpdate   map[int]RedisCommon.Rule
-	rulesToDelete   map[int]RedisCommon.Rule
-	rdb             *redis.Client
-	applicationName string
+	parentModel  tea.Model
+	inputMode    textinput.Model
+	order        []RedisCommon.OrderedRule
+	store        RedisCommon.SmartCacheStore
+	baseId       string
+	baseRules    []RedisCommon.Rule
+	stale        error
+	staleChanges string
+	impact       RedisCommon.RuleImpact
+	impactErr    error
 }
 
-func New(parentModel tea.Model, rulesToAdd []RedisCommon.Rule, rulesToUpdate map[int]RedisCommon.Rule, rulesToDelete map[int]RedisCommon.Rule, rdb *redis.Client, applicationName string) Model {
+func New(parentModel tea.Model, baseId string, baseRules []RedisCommon.Rule, order []RedisCommon.OrderedRule, store RedisCommon.SmartCacheStore) Model {
 	ti := textinput.New()
 	ti.Focus()
+	impact, err := RedisCommon.PreviewRuleUpdates(store, baseRules, order)
 	return Model{
-		parentModel:     parentModel,
-		inputMode:       ti,
-		rulesToAdd:      rulesToAdd,
-		rulesToDelete:   rulesToDelete,
-		rulesToUpdate:   rulesToUpdate,
-		rdb:             rdb,
-		applicationName: applicationName,
+		parentModel: parentModel,
+		inputMode:   ti,
+		order:       order,
+		store:       store,
+		baseId:      baseId,
+		baseRules:   baseRules,
+		impact:      impact,
+		impactErr:   err,
 	}
 }
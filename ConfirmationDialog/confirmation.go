@@ -35,7 +35,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.Confirmed = true
 				return m, tea.Quit
 			}
-			m.parentModel, _ = m.parentModel.Update(ConfirmationMessage{
+			m.parentModel, cmd = m.parentModel.Update(ConfirmationMessage{
 				Message:         "Rule Updates Committed to Redis.",
 				ConfirmedUpdate: true,
 			})
@@ -49,7 +49,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.parentModel.Update(ConfirmationMessage{
 				ConfirmedUpdate: false,
 			})
-			return m.parentModel, cmd
+			return m.parentModel, m.parentModel.Init()
 
 		}
 	}
@@ -66,12 +66,22 @@ func (m Model) View() string {
 
 	body.WriteString(fmt.Sprintf("Would you like to commit the following caching %s?\n", noun))
 	body.WriteString("============= Rules to Commit ==============\n")
+	if m.position != RedisCommon.TopPosition {
+		body.WriteString(fmt.Sprintf("Inserted at position: %s\n", m.position))
+	}
 	for _, r := range m.pendingRules {
 		body.WriteString(r.Formatted())
 		body.WriteString("\n")
 
 	}
 
+	body.WriteString("============= Impact on Queries ============\n")
+	if m.impactErr != nil {
+		body.WriteString(fmt.Sprintf("Could not preview the impact: %v\n", m.impactErr))
+	} else {
+		body.WriteString(m.impact.Formatted())
+	}
+
 	body.WriteString("============================================\n")
 	body.WriteString("(y)es / (N)o?")
 	return body.String()
@@ -81,15 +91,33 @@ type Model struct {
 	parentModel  tea.Model
 	inputMode    textinput.Model
 	pendingRules map[string]RedisCommon.Rule
+	impact       RedisCommon.RuleImpact
+	impactErr    error
+	position     RedisCommon.RulePosition
 	Confirmed    bool
 }
 
-func New(parentModel tea.Model, pendingRules map[string]RedisCommon.Rule) Model {
+func New(parentModel tea.Model, pendingRules map[string]RedisCommon.Rule, store RedisCommon.SmartCacheStore) Model {
+	return NewAt(parentModel, pendingRules, store, RedisCommon.TopPosition)
+}
+
+// NewAt asks to confirm rules that will be inserted at position rather than ahead of the current rules.
+func NewAt(parentModel tea.Model, pendingRules map[string]RedisCommon.Rule, store RedisCommon.SmartCacheStore, position RedisCommon.RulePosition) Model {
 	ti := textinput.New()
 	ti.Focus()
+
+	rules := make([]RedisCommon.Rule, 0, len(pendingRules))
+	for _, r := range pendingRules {
+		rules = append(rules, r)
+	}
+	impact, err := RedisCommon.PreviewNewRules(store, rules, position)
+
 	return Model{
 		parentModel:  parentModel,
 		inputMode:    ti,
 		pendingRules: pendingRules,
+		impact:       impact,
+		impactErr:    err,
+		position:     position,
 	}
 }
@@ -0,0 +1,99 @@
+package ExplainView
+
+import (
+	"fmt"
+	"smart-cache-cli/RedisCommon"
+	"smart-cache-cli/util"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	winnerStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
+	shadowedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	noMatchStyle  = lipgloss.NewStyle().Faint(true)
+)
+
+type Model struct {
+	parentModel tea.Model
+	query       *RedisCommon.Query
+	explanation RedisCommon.QueryExplanation
+	width       int
+	err         error
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyMsg:
+		switch msg.String() {
+		case tea.KeyCtrlC.String():
+			m.parentModel, _ = m.parentModel.Update(msg)
+			return m.parentModel, tea.Quit
+		case tea.KeyCtrlB.String(), tea.KeyEsc.String(), "b":
+			return m.parentModel, m.parentModel.Init()
+		}
+	}
+	return m, nil
+}
+
+func (m Model) View() string {
+	body := strings.Builder{}
+	body.WriteString(m.query.Formatted(m.width))
+	body.WriteString("\n\nPress ctrl+b or escape to return to the previous screen.\n\n")
+
+	if m.err != nil {
+		body.WriteString(util.StatusLine(m.err))
+		return body.String()
+	}
+
+	e := m.explanation
+	if e.Winner >= 0 {
+		body.WriteString(winnerStyle.Render(e.Summary()))
+	} else {
+		body.WriteString(e.Summary())
+	}
+	body.WriteString("\n\n")
+
+	for _, r := range e.Rules {
+		style := noMatchStyle
+		marker := "  "
+		if r.Index == e.Winner {
+			style = winnerStyle
+			marker = "=>"
+		} else if r.Explanation.Matched {
+			style = shadowedStyle
+		}
+
+		body.WriteString(style.Render(fmt.Sprintf("%s Rule %d: %s  %s", marker, r.Index, r.Rule.Summary(), e.Verdict(r))))
+		body.WriteString("\n")
+		for _, c := range r.Explanation.Conditions {
+			result := "✗"
+			if c.Matched {
+				result = "✓"
+			}
+			body.WriteString(fmt.Sprintf("     %s %s: %s\n", result, c.Condition, c.Reason))
+		}
+	}
+
+	return body.String()
+}
+
+// New explains which of the current rules applies to the query.
+func New(query *RedisCommon.Query, store RedisCommon.SmartCacheStore, parentModel tea.Model, width int) Model {
+	rules, err := store.GetRules()
+	return Model{
+		parentModel: parentModel,
+		query:       query,
+		explanation: RedisCommon.ExplainQuery(query, rules),
+		width:       width,
+		err:         err,
+	}
+}
@@ -0,0 +1,57 @@
+package RedisCommon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrIndexMissing is returned when the Smart Cache query index does not exist for the application.
+	ErrIndexMissing = errors.New("Redis Smart Cache index not found")
+	// ErrRulesOutOfSync is returned when a rule update refers to rules that are no longer in the config stream.
+	ErrRulesOutOfSync = errors.New("rules out of sync")
+	// ErrInvalidRulePosition is returned for a position to insert new rules at that is not among the current rules.
+	ErrInvalidRulePosition = errors.New("invalid rule position")
+	// ErrUnexpectedReply is returned when Redis replies with a shape Smart Cache does not produce.
+	ErrUnexpectedReply = errors.New("unexpected reply from Redis")
+	// ErrModuleMissing is returned when a command of RediSearch or RedisTimeSeries is not available on the server.
+	ErrModuleMissing = errors.New("required Redis module not loaded")
+)
+
+func unexpectedReply(command string, reply interface{}) error {
+	return fmt.Errorf("%w: %s returned %T", ErrUnexpectedReply, command, reply)
+}
+
+// wrapCommandError classifies errors from module commands so that callers can match them with errors.Is.
+func wrapCommandError(err error, command string, module string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "unknown command") {
+		return fmt.Errorf("%w: %s is required for %s (%v)", ErrModuleMissing, module, command, err)
+	}
+
+	if strings.Contains(msg, "no such index") || strings.Contains(msg, "unknown index name") {
+		return fmt.Errorf("%w: %s (%v)", ErrIndexMissing, command, err)
+	}
+
+	return fmt.Errorf("%s: %w", command, err)
+}
+
+// StaleRulesError is returned when a rule commit was made against a config stream entry that is no longer the head
+// of the stream, i.e. someone else committed rules in the meantime.
+type StaleRulesError struct {
+	ExpectedId string
+	CurrentId  string
+}
+
+func (e *StaleRulesError) Error() string {
+	return fmt.Sprintf("rules were changed concurrently: loaded from config entry '%s' but the latest entry is '%s'", e.ExpectedId, e.CurrentId)
+}
+
+func (e *StaleRulesError) Unwrap() error {
+	return ErrRulesOutOfSync
+}
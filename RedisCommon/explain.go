@@ -0,0 +1,80 @@
+package RedisCommon
+
+import (
+	"fmt"
+	"smart-cache-cli/RuleMatcher"
+	"strings"
+)
+
+// RuleExplanation is the outcome of evaluating one rule against a query. Index is the rule's precedence, as shown in
+// the rule list.
+type RuleExplanation struct {
+	Index       int
+	Rule        Rule
+	Explanation RuleMatcher.Explanation
+}
+
+// QueryExplanation walks every rule in precedence order for a query. Winner is the index of the rule that applies,
+// or -1 if the query is not cached.
+type QueryExplanation struct {
+	Query  *Query
+	Rules  []RuleExplanation
+	Winner int
+}
+
+func ExplainQuery(query *Query, rules []Rule) QueryExplanation {
+	subject := query.Subject()
+	explanation := QueryExplanation{Query: query, Rules: make([]RuleExplanation, len(rules)), Winner: -1}
+	for i, r := range rules {
+		explanation.Rules[i] = RuleExplanation{Index: i, Rule: r, Explanation: RuleMatcher.Explain(r.Conditions(), subject)}
+		if explanation.Winner < 0 && explanation.Rules[i].Explanation.Matched {
+			explanation.Winner = i
+		}
+	}
+	return explanation
+}
+
+// Verdict describes the outcome of a rule for the query: whether it applies, did not match, or matched but is
+// shadowed by the winning rule.
+func (e QueryExplanation) Verdict(r RuleExplanation) string {
+	switch {
+	case r.Index == e.Winner:
+		return "APPLIES"
+	case r.Explanation.Matched:
+		return fmt.Sprintf("SHADOWED by rule %d", e.Winner)
+	default:
+		return "no match"
+	}
+}
+
+// Summary states which rule caches the query, if any.
+func (e QueryExplanation) Summary() string {
+	if e.Winner < 0 {
+		return fmt.Sprintf("Query %s is not cached, none of the %d rules match it.", e.Query.Id, len(e.Rules))
+	}
+	if e.Rules[e.Winner].Rule.DisablesCaching() {
+		return fmt.Sprintf("Query %s is not cached, rule %d disables caching for it (%s).", e.Query.Id, e.Winner, e.Rules[e.Winner].Rule.Summary())
+	}
+	return fmt.Sprintf("Query %s is cached for %s by rule %d (%s).", e.Query.Id, e.Rules[e.Winner].Rule.Ttl, e.Winner, e.Rules[e.Winner].Rule.Summary())
+}
+
+// Formatted renders the explanation with one entry per rule, the winning rule marked with an arrow.
+func (e QueryExplanation) Formatted() string {
+	b := strings.Builder{}
+	b.WriteString(e.Summary() + "\n\n")
+	for _, r := range e.Rules {
+		marker := "  "
+		if r.Index == e.Winner {
+			marker = "=>"
+		}
+		b.WriteString(fmt.Sprintf("%s %3d. %s: %s\n", marker, r.Index, r.Rule.Summary(), e.Verdict(r)))
+		for _, c := range r.Explanation.Conditions {
+			result := "no"
+			if c.Matched {
+				result = "yes"
+			}
+			b.WriteString(fmt.Sprintf("         %-10s %-3s %s\n", c.Condition, result, c.Reason))
+		}
+	}
+	return b.String()
+}
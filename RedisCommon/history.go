@@ -0,0 +1,182 @@
+package RedisCommon
+
+import (
+	"errors"
+	"fmt"
+	"smart-cache-cli/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evertras/bubble-table/table"
+	"github.com/redis/go-redis/v9"
+)
+
+// historyTimeFormat is how entry timestamps are shown, in local time.
+const historyTimeFormat = "2006-01-02 15:04:05"
+
+// ErrEntryNotFound is returned when a config stream entry requested by ID does not exist.
+var ErrEntryNotFound = errors.New("config entry not found")
+
+// ConfigEntry is one past version of the rules, i.e. one entry of the config stream. Err is set instead of Rules
+// when the entry could not be parsed, so that a malformed entry does not hide the rest of the history.
+type ConfigEntry struct {
+	Id    string
+	Time  time.Time
+	Rules []Rule
+	Err   error
+}
+
+// EntryTime returns the time encoded in the millisecond part of an auto-generated stream entry ID.
+func EntryTime(id string) time.Time {
+	ms, _, _ := strings.Cut(id, "-")
+	i, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(i)
+}
+
+func newConfigEntry(msg redis.XMessage) ConfigEntry {
+	rules, err := ParseRules(msg.ID, msg.Values)
+	return ConfigEntry{Id: msg.ID, Time: EntryTime(msg.ID), Rules: rules, Err: err}
+}
+
+// Summary describes how the entry changed the rules compared to the entry before it, which is nil for the first one.
+func (e ConfigEntry) Summary(previous *ConfigEntry) string {
+	if e.Err != nil {
+		return fmt.Sprintf("unreadable: %v", e.Err)
+	}
+
+	if previous == nil {
+		return "initial rules"
+	}
+
+	if previous.Err != nil {
+		return "rules replaced"
+	}
+
+	added, removed := 0, 0
+	for _, c := range DiffRules(previous.Rules, e.Rules) {
+		switch c.Kind {
+		case Added:
+			added++
+		case Removed:
+			removed++
+		}
+	}
+
+	if added == 0 && removed == 0 {
+		return "no changes"
+	}
+
+	return fmt.Sprintf("+%d -%d", added, removed)
+}
+
+// NumRules returns the number of rules in the entry, or "?" if it could not be parsed.
+func (e ConfigEntry) NumRules() string {
+	if e.Err != nil {
+		return "?"
+	}
+	return strconv.Itoa(len(e.Rules))
+}
+
+// Previous returns the entry before entries[i] in a newest-first history, or nil if it is not part of entries.
+func Previous(entries []ConfigEntry, i int) *ConfigEntry {
+	if i+1 >= len(entries) {
+		return nil
+	}
+	return &entries[i+1]
+}
+
+func GetColumnsOfHistory() []table.Column {
+	return []table.Column{
+		makeColumn("Entry Id", "Entry Id", 20),
+		makeColumn("Time", "Time", 22),
+		makeColumn("Rules", "Rules", 8),
+		makeColumn("Summary", "Summary", 40),
+	}
+}
+
+func (e ConfigEntry) AsRow(rowId int, previous *ConfigEntry) table.Row {
+	return table.NewRow(table.RowData{
+		"Entry Id": e.Id,
+		"Time":     e.Time.Local().Format(historyTimeFormat),
+		"Rules":    e.NumRules(),
+		"Summary":  e.Summary(previous),
+		"RowId":    rowId,
+	})
+}
+
+func GetHistoryHeader(colWidth int) string {
+	row := "|"
+	row += util.CenterString("Entry Id", colWidth) + "|"
+	row += util.CenterString("Time", colWidth) + "|"
+	row += util.CenterString("Rules", colWidth) + "|"
+	row += util.CenterString("Summary", colWidth) + "|"
+	return row
+}
+
+func (e ConfigEntry) GetRow(colWidth int, previous *ConfigEntry) string {
+	row := "|"
+	row += util.CenterString(e.Id, colWidth) + "|"
+	row += util.CenterString(e.Time.Local().Format(historyTimeFormat), colWidth) + "|"
+	row += util.CenterString(e.NumRules(), colWidth) + "|"
+	row += util.CenterString(e.Summary(previous), colWidth) + "|"
+	return row
+}
+
+// GetRuleHistory returns up to count entries of the config stream, newest first. A count of zero or less returns
+// the whole stream.
+func GetRuleHistory(rdb redis.UniversalClient, applicationName string, count int64) ([]ConfigEntry, error) {
+	key := fmt.Sprintf("%s:config", applicationName)
+
+	var res []redis.XMessage
+	var err error
+	if count > 0 {
+		res, err = rdb.XRevRangeN(ctx, key, "+", "-", count).Result()
+	} else {
+		res, err = rdb.XRevRange(ctx, key, "+", "-").Result()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ConfigEntry, len(res))
+	for i, msg := range res {
+		entries[i] = newConfigEntry(msg)
+	}
+
+	return entries, nil
+}
+
+// GetRuleSetAt returns the rules of the config stream entry with the given ID.
+func GetRuleSetAt(rdb redis.UniversalClient, applicationName string, entryId string) (RuleSet, error) {
+	res, err := rdb.XRangeN(ctx, fmt.Sprintf("%s:config", applicationName), entryId, entryId, 1).Result()
+	if err != nil {
+		return RuleSet{}, err
+	}
+
+	if len(res) < 1 {
+		return RuleSet{}, fmt.Errorf("%w: %s", ErrEntryNotFound, entryId)
+	}
+
+	rules, err := ParseRules(res[0].ID, res[0].Values)
+	if err != nil {
+		return RuleSet{}, err
+	}
+
+	return RuleSet{Id: res[0].ID, Rules: rules}, nil
+}
+
+// RollbackRules re-publishes the rules of entry entryId as the new head of the config stream. Like UpdateRules it
+// refuses to overwrite changes committed after baseId was loaded.
+func RollbackRules(rdb redis.UniversalClient, baseId string, entryId string, applicationName string) (string, error) {
+	ruleSet, err := GetRuleSetAt(rdb, applicationName, entryId)
+	if err != nil {
+		return "", err
+	}
+
+	return CommitRuleSet(rdb, baseId, ruleSet.Rules, applicationName)
+}
@@ -0,0 +1,167 @@
+package RedisCommon
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryImpact is how a rule change affects the caching of one query. An empty TTL means the query is not cached,
+// either because no rule matches it or because the rule that does disables caching.
+type QueryImpact struct {
+	Query  *Query
+	OldTtl string
+	NewTtl string
+}
+
+// RuleImpact groups the queries whose caching a rule change affects.
+type RuleImpact struct {
+	NewlyCached []QueryImpact
+	TtlChanged  []QueryImpact
+	Uncached    []QueryImpact
+}
+
+func ruleTtl(rules []Rule, query *Query) string {
+	MatchRule(query, rules)
+	if query.Rule == nil || query.Rule.DisablesCaching() {
+		return ""
+	}
+	return NormalizeTtl(query.Rule.Ttl)
+}
+
+// PreviewImpact compares the rule that applies to each query under the current and the proposed rules.
+func PreviewImpact(queries []*Query, current []Rule, proposed []Rule) RuleImpact {
+	impact := RuleImpact{
+		NewlyCached: make([]QueryImpact, 0),
+		TtlChanged:  make([]QueryImpact, 0),
+		Uncached:    make([]QueryImpact, 0),
+	}
+
+	for _, q := range queries {
+		// MatchRule sets the rule of the query, work on a copy to leave the caller's query as it was.
+		cpy := *q
+		qi := QueryImpact{Query: q, OldTtl: ruleTtl(current, &cpy), NewTtl: ruleTtl(proposed, &cpy)}
+		switch {
+		case qi.OldTtl == qi.NewTtl:
+		case qi.OldTtl == "":
+			impact.NewlyCached = append(impact.NewlyCached, qi)
+		case qi.NewTtl == "":
+			impact.Uncached = append(impact.Uncached, qi)
+		default:
+			impact.TtlChanged = append(impact.TtlChanged, qi)
+		}
+	}
+
+	return impact
+}
+
+// PreviewNewRules previews the impact of inserting rules at position among the current rules, as CommitNewRules does.
+func PreviewNewRules(store SmartCacheStore, rules []Rule, position RulePosition) (RuleImpact, error) {
+	current, err := store.GetRules()
+	if err != nil {
+		return RuleImpact{}, err
+	}
+
+	queries, err := store.GetQueries()
+	if err != nil {
+		return RuleImpact{}, err
+	}
+
+	proposed, err := insertRules(rules, current, position)
+	if err != nil {
+		return RuleImpact{}, err
+	}
+
+	return PreviewImpact(queries, current, proposed), nil
+}
+
+// PreviewRuleUpdates previews the impact of replacing baseRules with the rules of order, as UpdateRules does.
+func PreviewRuleUpdates(store SmartCacheStore, baseRules []Rule, order []OrderedRule) (RuleImpact, error) {
+	proposed, err := applyRuleOrder(baseRules, order)
+	if err != nil {
+		return RuleImpact{}, err
+	}
+
+	queries, err := store.GetQueries()
+	if err != nil {
+		return RuleImpact{}, err
+	}
+
+	return PreviewImpact(queries, baseRules, proposed), nil
+}
+
+// Affected returns every query the change affects.
+func (i RuleImpact) Affected() []QueryImpact {
+	res := make([]QueryImpact, 0, len(i.NewlyCached)+len(i.TtlChanged)+len(i.Uncached))
+	res = append(res, i.NewlyCached...)
+	res = append(res, i.TtlChanged...)
+	return append(res, i.Uncached...)
+}
+
+// AccessFrequency is the total access frequency of the affected queries.
+func (i RuleImpact) AccessFrequency() int {
+	total := 0
+	for _, qi := range i.Affected() {
+		total += qi.Query.Count
+	}
+	return total
+}
+
+// MeanTime is the mean query time of the affected queries, weighted by how often each is accessed.
+func (i RuleImpact) MeanTime() float64 {
+	affected := i.Affected()
+	total := i.AccessFrequency()
+	if len(affected) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, qi := range affected {
+		if total == 0 {
+			sum += qi.Query.MeanTime / float64(len(affected))
+		} else {
+			sum += qi.Query.MeanTime * float64(qi.Query.Count) / float64(total)
+		}
+	}
+	return sum
+}
+
+func pluralQueries(n int) string {
+	if n == 1 {
+		return "1 query"
+	}
+	return fmt.Sprintf("%d queries", n)
+}
+
+func formatQueryImpacts(b *strings.Builder, title string, impacts []QueryImpact) {
+	if len(impacts) == 0 {
+		return
+	}
+
+	b.WriteString(fmt.Sprintf("%s: %s\n", title, pluralQueries(len(impacts))))
+	for _, qi := range impacts {
+		oldTtl, newTtl := qi.OldTtl, qi.NewTtl
+		if oldTtl == "" {
+			oldTtl = "not cached"
+		}
+		if newTtl == "" {
+			newTtl = "not cached"
+		}
+		b.WriteString(fmt.Sprintf("  %s %s -> %s (%d accesses, %.2fms): %s\n", qi.Query.Id, oldTtl, newTtl, qi.Query.Count, qi.Query.MeanTime, qi.Query.Sql))
+	}
+}
+
+// Formatted lists the affected queries by kind of change, followed by their total access frequency and mean time.
+func (i RuleImpact) Formatted() string {
+	affected := i.Affected()
+	if len(affected) == 0 {
+		return "The change does not affect the caching of any query.\n"
+	}
+
+	b := strings.Builder{}
+	formatQueryImpacts(&b, "Newly cached", i.NewlyCached)
+	formatQueryImpacts(&b, "TTL changed", i.TtlChanged)
+	formatQueryImpacts(&b, "No longer cached", i.Uncached)
+	b.WriteString(fmt.Sprintf("Affects %s with a total access frequency of %d and a mean query time of %.2fms.\n",
+		pluralQueries(len(affected)), i.AccessFrequency(), i.MeanTime()))
+	return b.String()
+}
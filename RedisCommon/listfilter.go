@@ -0,0 +1,176 @@
+package RedisCommon
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"smart-cache-cli/RuleMatcher"
+)
+
+// The keys the list commands sort by.
+const (
+	SortByQueryTime       = "querytime"
+	SortByAccessFrequency = "accessfrequency"
+	SortByTables          = "tables"
+	SortById              = "id"
+	SortByName            = "name"
+	SortByTtl             = "ttl"
+)
+
+// QuerySortKeys and TableSortKeys are the keys SortQueries and SortTables accept.
+var (
+	QuerySortKeys = []string{SortByQueryTime, SortByAccessFrequency, SortByTables, SortById, SortByTtl}
+	TableSortKeys = []string{SortByQueryTime, SortByAccessFrequency, SortByName, SortByTtl}
+)
+
+// ListFilter selects the queries or tables the list commands show. Zero values do not filter.
+type ListFilter struct {
+	// Table keeps the queries that use the table, or the table of that name.
+	Table string
+	// SqlRegex keeps the queries whose SQL it matches anywhere.
+	SqlRegex *regexp.Regexp
+	// Cached keeps the cached queries or tables, Uncached the others.
+	Cached   bool
+	Uncached bool
+	// MinCount is the lowest access frequency to keep.
+	MinCount uint64
+	// MinMeanMs is the lowest mean query time to keep, in milliseconds.
+	MinMeanMs float64
+	// RuleIndex keeps the queries or tables that the rule at this index applies to, when not nil.
+	RuleIndex *int
+	// Limit is the maximum number to keep, applied after sorting.
+	Limit int
+}
+
+// keepCaching tells from the caching of the rule that applies, as CachingStatus shows it, whether to keep a query or
+// table.
+func (f ListFilter) keepCaching(rule *Rule) bool {
+	cached := RuleCaching(rule) == CachingOn
+	return (!f.Cached || cached) && (!f.Uncached || !cached)
+}
+
+// FilterQueries returns the queries the filter keeps. The caching and rule index filters both go by the rule the
+// queries were matched to when they were loaded.
+func (f ListFilter) FilterQueries(queries []*Query) []*Query {
+	res := make([]*Query, 0, len(queries))
+	for _, q := range queries {
+		switch {
+		case f.Table != "" && !contains(RuleMatcher.ParseTables(q.Table), f.Table):
+		case f.SqlRegex != nil && !f.SqlRegex.MatchString(q.Sql):
+		case !f.keepCaching(q.Rule):
+		case uint64(q.Count) < f.MinCount:
+		case q.MeanTime < f.MinMeanMs:
+		case f.RuleIndex != nil && q.RuleIndex != *f.RuleIndex:
+		default:
+			res = append(res, q)
+		}
+	}
+	return res
+}
+
+// FilterTables returns the tables the filter keeps. The caching and rule index filters both go by the rule the tables
+// were matched to when they were loaded.
+func (f ListFilter) FilterTables(tables []Table) []Table {
+	res := make([]Table, 0, len(tables))
+	for _, t := range tables {
+		switch {
+		case f.Table != "" && t.Name != f.Table:
+		case !f.keepCaching(t.Rule):
+		case t.AccessFrequency < f.MinCount:
+		case t.QueryTime < f.MinMeanMs:
+		case f.RuleIndex != nil && t.RuleIndex != *f.RuleIndex:
+		default:
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// LimitQueries applies the limit of the filter.
+func (f ListFilter) LimitQueries(queries []*Query) []*Query {
+	if f.Limit > 0 && len(queries) > f.Limit {
+		return queries[:f.Limit]
+	}
+	return queries
+}
+
+// LimitTables applies the limit of the filter.
+func (f ListFilter) LimitTables(tables []Table) []Table {
+	if f.Limit > 0 && len(tables) > f.Limit {
+		return tables[:f.Limit]
+	}
+	return tables
+}
+
+func invalidSortKey(key string, valid []string) error {
+	return fmt.Errorf("'%s' is not a valid sort key, valid keys are %s", key, strings.Join(valid, ", "))
+}
+
+// CheckSortKey returns the error SortQueries or SortTables would return if key is not one of valid, so that the key
+// can be checked before loading anything.
+func CheckSortKey(key string, valid []string) error {
+	if !contains(valid, strings.ToLower(key)) {
+		return invalidSortKey(key, valid)
+	}
+	return nil
+}
+
+// SortQueries sorts the queries by one of QuerySortKeys, case-insensitively. Queries that are not cached sort before
+// the cached ones by TTL.
+func SortQueries(queries []*Query, key string, descending bool) error {
+	var less func(a *Query, b *Query) bool
+	switch strings.ToLower(key) {
+	case SortByQueryTime:
+		less = func(a *Query, b *Query) bool { return a.MeanTime < b.MeanTime }
+	case SortByAccessFrequency:
+		less = func(a *Query, b *Query) bool { return a.Count < b.Count }
+	case SortByTables:
+		less = func(a *Query, b *Query) bool { return a.Table < b.Table }
+	case SortById:
+		less = func(a *Query, b *Query) bool { return a.Id < b.Id }
+	case SortByTtl:
+		less = func(a *Query, b *Query) bool {
+			return TtlSortValue(cachedTtl(a.Rule)) < TtlSortValue(cachedTtl(b.Rule))
+		}
+	default:
+		return invalidSortKey(key, QuerySortKeys)
+	}
+
+	sort.SliceStable(queries, func(i int, j int) bool {
+		if descending {
+			return less(queries[j], queries[i])
+		}
+		return less(queries[i], queries[j])
+	})
+	return nil
+}
+
+// SortTables sorts the tables by one of TableSortKeys, case-insensitively. Tables whose queries are not cached sort
+// before the cached ones by TTL.
+func SortTables(tables []Table, key string, descending bool) error {
+	var less func(a Table, b Table) bool
+	switch strings.ToLower(key) {
+	case SortByQueryTime:
+		less = func(a Table, b Table) bool { return a.QueryTime < b.QueryTime }
+	case SortByAccessFrequency:
+		less = func(a Table, b Table) bool { return a.AccessFrequency < b.AccessFrequency }
+	case SortByName:
+		less = func(a Table, b Table) bool { return a.Name < b.Name }
+	case SortByTtl:
+		less = func(a Table, b Table) bool {
+			return TtlSortValue(cachedTtl(a.Rule)) < TtlSortValue(cachedTtl(b.Rule))
+		}
+	default:
+		return invalidSortKey(key, TableSortKeys)
+	}
+
+	sort.SliceStable(tables, func(i int, j int) bool {
+		if descending {
+			return less(tables[j], tables[i])
+		}
+		return less(tables[i], tables[j])
+	})
+	return nil
+}
@@ -0,0 +1,191 @@
+package RedisCommon
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func listQueries() []*Query {
+	fiveMinutes, oneHour, noCache := tableRule("orders"), Rule{TablesAny: []string{"orders"}, Ttl: "1h"}, Rule{TablesAny: []string{"customers"}, Ttl: "0s"}
+	return []*Query{
+		{Id: "q1", Table: "orders", Sql: "SELECT * FROM orders", Count: 10, MeanTime: 5, Rule: &fiveMinutes, RuleIndex: 0},
+		{Id: "q2", Table: "orders,customers", Sql: "SELECT * FROM orders JOIN customers", Count: 3, MeanTime: 20, Rule: &oneHour, RuleIndex: 1},
+		{Id: "q3", Table: "customers", Sql: "SELECT name FROM customers", Count: 50, MeanTime: 1, Rule: &noCache, RuleIndex: 2},
+		{Id: "q4", Table: "products", Sql: "SELECT * FROM products", Count: 7, MeanTime: 8, RuleIndex: -1},
+	}
+}
+
+func listTables() []Table {
+	fiveMinutes, noCache := tableRule("orders"), Rule{TablesAny: []string{"customers"}, Ttl: "0s"}
+	return []Table{
+		{Name: "orders", AccessFrequency: 13, QueryTime: 12.5, Rule: &fiveMinutes, RuleIndex: 0},
+		{Name: "customers", AccessFrequency: 53, QueryTime: 10.5, Rule: &noCache, RuleIndex: 2},
+		{Name: "products", AccessFrequency: 7, QueryTime: 8, RuleIndex: -1},
+	}
+}
+
+func queryIds(queries []*Query) []string {
+	ids := make([]string, len(queries))
+	for i, q := range queries {
+		ids[i] = q.Id
+	}
+	return ids
+}
+
+func tableNames(tables []Table) []string {
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestFilterQueries(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"no filter", ListFilter{}, []string{"q1", "q2", "q3", "q4"}},
+		{"table", ListFilter{Table: "customers"}, []string{"q2", "q3"}},
+		{"sql regex", ListFilter{SqlRegex: regexp.MustCompile(`JOIN`)}, []string{"q2"}},
+		{"cached", ListFilter{Cached: true}, []string{"q1", "q2"}},
+		{"uncached", ListFilter{Uncached: true}, []string{"q3", "q4"}},
+		{"min count", ListFilter{MinCount: 7}, []string{"q1", "q3", "q4"}},
+		{"min mean", ListFilter{MinMeanMs: 8}, []string{"q2", "q4"}},
+		{"rule index", ListFilter{RuleIndex: intPtr(1)}, []string{"q2"}},
+		{"table and cached", ListFilter{Table: "customers", Cached: true}, []string{"q2"}},
+		{"table and uncached", ListFilter{Table: "customers", Uncached: true}, []string{"q3"}},
+		{"min count and min mean", ListFilter{MinCount: 5, MinMeanMs: 5}, []string{"q1", "q4"}},
+		{"regex and rule index", ListFilter{SqlRegex: regexp.MustCompile(`\*`), RuleIndex: intPtr(2)}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := queryIds(tt.filter.FilterQueries(listQueries())); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterQueries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"no filter", ListFilter{}, []string{"orders", "customers", "products"}},
+		{"table", ListFilter{Table: "customers"}, []string{"customers"}},
+		{"cached", ListFilter{Cached: true}, []string{"orders"}},
+		{"uncached", ListFilter{Uncached: true}, []string{"customers", "products"}},
+		{"min count", ListFilter{MinCount: 13}, []string{"orders", "customers"}},
+		{"min mean", ListFilter{MinMeanMs: 10.5}, []string{"orders", "customers"}},
+		{"rule index", ListFilter{RuleIndex: intPtr(2)}, []string{"customers"}},
+		{"uncached and min count", ListFilter{Uncached: true, MinCount: 10}, []string{"customers"}},
+		{"table and cached", ListFilter{Table: "customers", Cached: true}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tableNames(tt.filter.FilterTables(listTables())); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimit(t *testing.T) {
+	filter := ListFilter{Limit: 2}
+	if got := queryIds(filter.LimitQueries(listQueries())); !reflect.DeepEqual(got, []string{"q1", "q2"}) {
+		t.Errorf("LimitQueries() = %v", got)
+	}
+	if got := tableNames(filter.LimitTables(listTables())); !reflect.DeepEqual(got, []string{"orders", "customers"}) {
+		t.Errorf("LimitTables() = %v", got)
+	}
+	if got := queryIds(ListFilter{Limit: 10}.LimitQueries(listQueries())); len(got) != 4 {
+		t.Errorf("LimitQueries() = %v, want every query", got)
+	}
+}
+
+func TestSortQueries(t *testing.T) {
+	tests := []struct {
+		key        string
+		descending bool
+		want       []string
+	}{
+		{"queryTime", false, []string{"q3", "q1", "q4", "q2"}},
+		{"QUERYTIME", true, []string{"q2", "q4", "q1", "q3"}},
+		{"accessFrequency", false, []string{"q2", "q4", "q1", "q3"}},
+		{"tables", false, []string{"q3", "q1", "q2", "q4"}},
+		{"id", true, []string{"q4", "q3", "q2", "q1"}},
+		// Queries that are not cached sort first by TTL, in their original order.
+		{"ttl", false, []string{"q3", "q4", "q1", "q2"}},
+		{"ttl", true, []string{"q2", "q1", "q3", "q4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			queries := listQueries()
+			if err := CheckSortKey(tt.key, QuerySortKeys); err != nil {
+				t.Errorf("CheckSortKey() error = %v", err)
+			}
+			if err := SortQueries(queries, tt.key, tt.descending); err != nil {
+				t.Fatalf("SortQueries() error = %v", err)
+			}
+			if got := queryIds(queries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortQueries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortTables(t *testing.T) {
+	tests := []struct {
+		key        string
+		descending bool
+		want       []string
+	}{
+		{"queryTime", false, []string{"products", "customers", "orders"}},
+		{"accessFrequency", true, []string{"customers", "orders", "products"}},
+		{"name", false, []string{"customers", "orders", "products"}},
+		{"ttl", false, []string{"customers", "products", "orders"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			tables := listTables()
+			if err := CheckSortKey(tt.key, TableSortKeys); err != nil {
+				t.Errorf("CheckSortKey() error = %v", err)
+			}
+			if err := SortTables(tables, tt.key, tt.descending); err != nil {
+				t.Fatalf("SortTables() error = %v", err)
+			}
+			if got := tableNames(tables); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownSortKey(t *testing.T) {
+	want := "'name' is not a valid sort key, valid keys are querytime, accessfrequency, tables, id, ttl"
+	if err := SortQueries(listQueries(), "name", false); err == nil || err.Error() != want {
+		t.Errorf("SortQueries() error = %v, want %s", err, want)
+	}
+	if err := CheckSortKey("name", QuerySortKeys); err == nil || err.Error() != want {
+		t.Errorf("CheckSortKey() error = %v, want %s", err, want)
+	}
+
+	if err := SortTables(listTables(), "id", false); err == nil {
+		t.Error("SortTables() error = nil, want an invalid sort key")
+	}
+	if err := CheckSortKey("id", TableSortKeys); err == nil {
+		t.Error("CheckSortKey() error = nil, want an invalid sort key")
+	}
+}
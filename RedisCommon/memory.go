@@ -0,0 +1,272 @@
+package RedisCommon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixtures is the JSON layout the in-memory store is seeded from:
+//
+//	{
+//	  "application": "smartcache",
+//	  "queries": [
+//	    {"id": "a1b2", "table": "orders,customers", "sql": "SELECT ...", "count": 120, "meanTime": 4.2}
+//	  ],
+//	  "rules": [
+//	    {"tablesAny": ["orders"], "ttl": "5m"}
+//	  ]
+//	}
+type Fixtures struct {
+	Application string         `json:"application"`
+	Queries     []FixtureQuery `json:"queries"`
+	Rules       []Rule         `json:"rules"`
+}
+
+// FixtureQuery is a query as Smart Cache records it, its metadata hash together with its latest statistics.
+type FixtureQuery struct {
+	Id       string  `json:"id"`
+	Table    string  `json:"table"`
+	Sql      string  `json:"sql"`
+	Count    int     `json:"count"`
+	MeanTime float64 `json:"meanTime"`
+}
+
+// MemoryStore is a SmartCacheStore held entirely in memory, used to develop and test against fixtures without a
+// Redis Stack server. Rule commits are kept for the lifetime of the store, together with their history.
+type MemoryStore struct {
+	mu              sync.Mutex
+	applicationName string
+	queries         []FixtureQuery
+	rules           []Rule
+	lastMs          int64
+	lastSeq         int64
+	headId          string
+	history         []ConfigEntry
+}
+
+func NewMemoryStore(applicationName string, queries []FixtureQuery, rules []Rule) *MemoryStore {
+	s := &MemoryStore{
+		applicationName: applicationName,
+		queries:         queries,
+		rules:           rules,
+	}
+	if len(rules) > 0 {
+		s.nextId()
+	}
+	return s
+}
+
+// LoadMemoryStore seeds a MemoryStore from a JSON fixtures file. The application name in the file, when present,
+// takes precedence over applicationName.
+func LoadMemoryStore(path string, applicationName string) (*MemoryStore, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures Fixtures
+	err = json.Unmarshal(b, &fixtures)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse fixtures '%s': %w", path, err)
+	}
+
+	if fixtures.Application != "" {
+		applicationName = fixtures.Application
+	}
+
+	return NewMemoryStore(applicationName, fixtures.Queries, fixtures.Rules), nil
+}
+
+func (s *MemoryStore) ApplicationName() string {
+	return s.applicationName
+}
+
+func (s *MemoryStore) GetQueries() ([]*Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queries := make([]*Query, len(s.queries))
+	for i, fq := range s.queries {
+		queries[i] = &Query{
+			Id:       fq.Id,
+			Table:    fq.Table,
+			Sql:      fq.Sql,
+			Key:      fmt.Sprintf("%s:query:%s", s.applicationName, fq.Id),
+			Count:    fq.Count,
+			MeanTime: fq.MeanTime,
+		}
+		MatchRule(queries[i], s.rules)
+	}
+
+	return queries, nil
+}
+
+// GetTables aggregates the queries per table the same way the FT.AGGREGATE in GetTables does, summing the
+// access counts and averaging the mean query times.
+func (s *MemoryStore) GetTables() ([]Table, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byName := make(map[string]*Table)
+	numQueries := make(map[string]int)
+	names := make([]string, 0)
+	for _, q := range s.queries {
+		for _, name := range strings.Split(q.Table, ",") {
+			t, ok := byName[name]
+			if !ok {
+				t = &Table{Name: name}
+				byName[name] = t
+				names = append(names, name)
+			}
+			t.AccessFrequency += uint64(q.Count)
+			t.QueryTime += q.MeanTime
+			numQueries[name]++
+		}
+	}
+
+	sort.Strings(names)
+	tables := make([]Table, len(names))
+	for i, name := range names {
+		tables[i] = *byName[name]
+		tables[i].QueryTime /= float64(numQueries[name])
+		MatchTableRule(&tables[i], s.rules)
+	}
+
+	return tables, nil
+}
+
+func (s *MemoryStore) GetRules() ([]Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]Rule, len(s.rules))
+	copy(rules, s.rules)
+	return rules, nil
+}
+
+func (s *MemoryStore) GetRuleSet() (RuleSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]Rule, len(s.rules))
+	copy(rules, s.rules)
+	return RuleSet{Id: s.headId, Rules: rules}, nil
+}
+
+// nextId mimics the auto-generated IDs of XADD and records the current rules under the new ID.
+func (s *MemoryStore) nextId() string {
+	ms := time.Now().UnixMilli()
+	if ms > s.lastMs {
+		s.lastMs = ms
+		s.lastSeq = 0
+	} else {
+		s.lastSeq++
+	}
+	s.headId = fmt.Sprintf("%d-%d", s.lastMs, s.lastSeq)
+
+	rules := make([]Rule, len(s.rules))
+	copy(rules, s.rules)
+	s.history = append(s.history, ConfigEntry{Id: s.headId, Time: time.UnixMilli(s.lastMs), Rules: rules})
+	return s.headId
+}
+
+func (s *MemoryStore) CommitNewRules(rules []Rule, position RulePosition) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proposed, err := insertRules(rules, s.rules, position)
+	if err != nil {
+		return "", err
+	}
+	if err := checkRulesSerializable(proposed); err != nil {
+		return "", err
+	}
+
+	s.rules = proposed
+	return s.nextId(), nil
+}
+
+func (s *MemoryStore) UpdateRules(baseId string, order []OrderedRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if baseId != s.headId {
+		return &StaleRulesError{ExpectedId: baseId, CurrentId: s.headId}
+	}
+
+	rules, err := applyRuleOrder(s.rules, order)
+	if err != nil {
+		return err
+	}
+	if err := checkRulesSerializable(rules); err != nil {
+		return err
+	}
+
+	s.rules = rules
+	s.nextId()
+	return nil
+}
+
+func (s *MemoryStore) ReplaceRules(baseId string, rules []Rule) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if baseId != s.headId {
+		return "", &StaleRulesError{ExpectedId: baseId, CurrentId: s.headId}
+	}
+	if err := checkRulesSerializable(rules); err != nil {
+		return "", err
+	}
+
+	s.rules = make([]Rule, len(rules))
+	copy(s.rules, rules)
+	return s.nextId(), nil
+}
+
+func (s *MemoryStore) GetRuleHistory(count int64) ([]ConfigEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]ConfigEntry, 0, len(s.history))
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if count > 0 && int64(len(entries)) >= count {
+			break
+		}
+		entries = append(entries, s.history[i])
+	}
+
+	return entries, nil
+}
+
+func (s *MemoryStore) GetRuleSetAt(entryId string) (RuleSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.history {
+		if e.Id == entryId {
+			rules := make([]Rule, len(e.Rules))
+			copy(rules, e.Rules)
+			return RuleSet{Id: e.Id, Rules: rules}, nil
+		}
+	}
+
+	return RuleSet{}, fmt.Errorf("%w: %s", ErrEntryNotFound, entryId)
+}
+
+func (s *MemoryStore) RollbackRules(baseId string, entryId string) (string, error) {
+	ruleSet, err := s.GetRuleSetAt(entryId)
+	if err != nil {
+		return "", err
+	}
+
+	return s.ReplaceRules(baseId, ruleSet.Rules)
+}
+
+func (s *MemoryStore) CheckSmartCacheIndex() error {
+	return nil
+}
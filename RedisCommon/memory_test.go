@@ -0,0 +1,111 @@
+package RedisCommon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStaleCommit(t *testing.T) {
+	a, b := Rule{TablesAny: []string{"a"}, Ttl: "5m"}, Rule{TablesAny: []string{"b"}, Ttl: "1h"}
+	store := NewMemoryStore("test", nil, []Rule{a, b})
+
+	loaded, _ := store.GetRuleSet()
+	headId, err := store.CommitNewRules([]Rule{{TablesAny: []string{"x"}, Ttl: "1m"}}, TopPosition)
+	if err != nil {
+		t.Fatalf("CommitNewRules() error = %v", err)
+	}
+	committed, _ := store.GetRuleSet()
+
+	commits := []struct {
+		name   string
+		commit func() error
+	}{
+		{"UpdateRules", func() error {
+			return store.UpdateRules(loaded.Id, []OrderedRule{{1, b}, {0, a}})
+		}},
+		{"ReplaceRules", func() error {
+			_, err := store.ReplaceRules(loaded.Id, []Rule{b})
+			return err
+		}},
+	}
+
+	for _, c := range commits {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.commit()
+
+			var stale *StaleRulesError
+			if !errors.As(err, &stale) {
+				t.Fatalf("%s() error = %v, want a *StaleRulesError", c.name, err)
+			}
+			if stale.ExpectedId != loaded.Id || stale.CurrentId != headId {
+				t.Errorf("%s() error = %+v, want ExpectedId %s and CurrentId %s", c.name, stale, loaded.Id, headId)
+			}
+			if !errors.Is(err, ErrRulesOutOfSync) {
+				t.Errorf("%s() error = %v, want it to wrap ErrRulesOutOfSync", c.name, err)
+			}
+
+			if current, _ := store.GetRuleSet(); !reflect.DeepEqual(current, committed) {
+				t.Errorf("rules after a stale commit = %+v, want %+v", current, committed)
+			}
+		})
+	}
+}
+
+func TestStaleRulesError(t *testing.T) {
+	var stale *StaleRulesError
+	if err := staleRulesError("1-0", errors.New("STALE 2-0")); !errors.As(err, &stale) ||
+		*stale != (StaleRulesError{ExpectedId: "1-0", CurrentId: "2-0"}) {
+		t.Errorf("staleRulesError() = %v, want a *StaleRulesError from 1-0 to 2-0", err)
+	}
+
+	if err := staleRulesError("1-0", errors.New("STALE")); !errors.As(err, &stale) || stale.CurrentId != "" {
+		t.Errorf("staleRulesError() = %v, want a *StaleRulesError with an empty stream", err)
+	}
+
+	other := errors.New("NOSCRIPT No matching script")
+	if err := staleRulesError("1-0", other); err != other {
+		t.Errorf("staleRulesError() = %v, want %v", err, other)
+	}
+}
+
+func TestMemoryStoreRejectsUnserializableRules(t *testing.T) {
+	a := Rule{TablesAny: []string{"a"}, Ttl: "5m"}
+	emptyList := Rule{TablesAny: []string{}, Ttl: "5m"}
+
+	commits := []struct {
+		name   string
+		commit func(store *MemoryStore, headId string) error
+		// rules is the rule set the commit would store, which RedisStore fails to serialize.
+		rules []Rule
+	}{
+		{"CommitNewRules", func(store *MemoryStore, _ string) error {
+			_, err := store.CommitNewRules([]Rule{emptyList}, BottomPosition)
+			return err
+		}, []Rule{a, emptyList}},
+		{"UpdateRules", func(store *MemoryStore, headId string) error {
+			return store.UpdateRules(headId, []OrderedRule{{-1, emptyList}, {0, a}})
+		}, []Rule{emptyList, a}},
+		{"ReplaceRules", func(store *MemoryStore, headId string) error {
+			_, err := store.ReplaceRules(headId, []Rule{emptyList})
+			return err
+		}, []Rule{emptyList}},
+	}
+
+	for _, c := range commits {
+		t.Run(c.name, func(t *testing.T) {
+			store := NewMemoryStore("test", nil, []Rule{a})
+			before, _ := store.GetRuleSet()
+
+			err := c.commit(store, before.Id)
+			_, wantErr := serializeRules(c.rules)
+			if err == nil || wantErr == nil || err.Error() != wantErr.Error() {
+				t.Errorf("%s() error = %v, want %v", c.name, err, wantErr)
+			}
+
+			if after, _ := store.GetRuleSet(); !reflect.DeepEqual(after, before) {
+				t.Errorf("rules after a rejected commit = %+v, want %+v", after, before)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package RedisCommon
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/evertras/bubble-table/table"
+)
+
+// QueryDelta is how the access frequency and mean query time of a query changed between two loads of the queries.
+type QueryDelta struct {
+	Count    int
+	MeanTime float64
+}
+
+// Changed reports whether the query was accessed or its mean query time moved by at least the precision it is
+// displayed with.
+func (d QueryDelta) Changed() bool {
+	return d.Count != 0 || math.Abs(d.MeanTime) >= 0.005
+}
+
+// QueryDeltas compares two loads of the queries by query id. Queries that first appear in current count from zero.
+func QueryDeltas(previous []*Query, current []*Query) map[string]QueryDelta {
+	before := make(map[string]*Query, len(previous))
+	for _, q := range previous {
+		before[q.Id] = q
+	}
+
+	deltas := make(map[string]QueryDelta, len(current))
+	for _, q := range current {
+		d := QueryDelta{Count: q.Count, MeanTime: q.MeanTime}
+		if p, ok := before[q.Id]; ok {
+			d = QueryDelta{Count: q.Count - p.Count, MeanTime: q.MeanTime - p.MeanTime}
+		}
+		deltas[q.Id] = d
+	}
+	return deltas
+}
+
+// GetAsRowWithDelta is GetAsRow with the delta in the "Last Change" column, left blank if the query did not change.
+func (query *Query) GetAsRowWithDelta(rowId int, delta QueryDelta) table.Row {
+	row := query.GetAsRow(rowId)
+	row.Data["Last Change"] = ""
+	if delta.Changed() {
+		row.Data["Last Change"] = fmt.Sprintf("%+d, %+.2fms", delta.Count, delta.MeanTime)
+	}
+	return row
+}
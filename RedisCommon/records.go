@@ -0,0 +1,169 @@
+package RedisCommon
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Caching is whether the results of queries are cached, as written in machine-readable output. Unlike a TTL, it tells
+// a rule that disables caching apart from no rule applying.
+type Caching string
+
+const (
+	// CachingOn is for queries cached by the rule that applies to them.
+	CachingOn Caching = "enabled"
+	// CachingOff is for queries a no-cache rule applies to.
+	CachingOff Caching = "disabled"
+	// CachingUnset is for queries no rule applies to.
+	CachingUnset Caching = "unset"
+)
+
+// RuleCaching is the caching of the queries the rule applies to, nil being no rule.
+func RuleCaching(rule *Rule) Caching {
+	switch {
+	case rule == nil:
+		return CachingUnset
+	case rule.DisablesCaching():
+		return CachingOff
+	default:
+		return CachingOn
+	}
+}
+
+// QueryRecord is a query as it is written in machine-readable output, with every field in full. Ttl is empty when the
+// query is not cached and Rule is the rule that applies to the query, if any.
+type QueryRecord struct {
+	Id              string  `json:"id" yaml:"id"`
+	Key             string  `json:"key" yaml:"key"`
+	Table           string  `json:"table" yaml:"table"`
+	Sql             string  `json:"sql" yaml:"sql"`
+	AccessFrequency int     `json:"accessFrequency" yaml:"accessFrequency"`
+	MeanQueryTime   float64 `json:"meanQueryTime" yaml:"meanQueryTime"`
+	Caching         Caching `json:"caching" yaml:"caching"`
+	Ttl             string  `json:"ttl" yaml:"ttl"`
+	Rule            *Rule   `json:"rule" yaml:"rule"`
+}
+
+// TableRecord is a table as it is written in machine-readable output, Rule is the rule that applies to its queries.
+type TableRecord struct {
+	Name            string  `json:"name" yaml:"name"`
+	AccessFrequency uint64  `json:"accessFrequency" yaml:"accessFrequency"`
+	QueryTime       float64 `json:"queryTime" yaml:"queryTime"`
+	Caching         Caching `json:"caching" yaml:"caching"`
+	Ttl             string  `json:"ttl" yaml:"ttl"`
+	Rule            *Rule   `json:"rule" yaml:"rule"`
+}
+
+// cachedTtl is the normalized TTL the rule caches queries for, or empty if there is no rule or it disables caching.
+func cachedTtl(rule *Rule) string {
+	if rule == nil || rule.DisablesCaching() {
+		return ""
+	}
+	return NormalizeTtl(rule.Ttl)
+}
+
+func ruleSummaryOrEmptyString(rule *Rule) string {
+	if rule == nil {
+		return ""
+	}
+	return rule.Summary()
+}
+
+func (query *Query) Record() QueryRecord {
+	return QueryRecord{
+		Id:              query.Id,
+		Key:             query.Key,
+		Table:           query.Table,
+		Sql:             query.Sql,
+		AccessFrequency: query.Count,
+		MeanQueryTime:   query.MeanTime,
+		Caching:         RuleCaching(query.Rule),
+		Ttl:             cachedTtl(query.Rule),
+		Rule:            query.Rule,
+	}
+}
+
+func (t *Table) Record() TableRecord {
+	return TableRecord{
+		Name:            t.Name,
+		AccessFrequency: t.AccessFrequency,
+		QueryTime:       t.QueryTime,
+		Caching:         RuleCaching(t.Rule),
+		Ttl:             cachedTtl(t.Rule),
+		Rule:            t.Rule,
+	}
+}
+
+// Columns are the names of the fields Values returns, the header of CSV and TSV output.
+func (r QueryRecord) Columns() []string {
+	return []string{"id", "key", "table", "sql", "accessFrequency", "meanQueryTime", "caching", "ttl", "rule"}
+}
+
+// Values flattens the record into one value per column, the rule is summarized.
+func (r QueryRecord) Values() []string {
+	return []string{
+		r.Id,
+		r.Key,
+		r.Table,
+		r.Sql,
+		strconv.Itoa(r.AccessFrequency),
+		strconv.FormatFloat(r.MeanQueryTime, 'f', -1, 64),
+		string(r.Caching),
+		r.Ttl,
+		ruleSummaryOrEmptyString(r.Rule),
+	}
+}
+
+// Columns are the names of the fields Values returns, the header of CSV and TSV output.
+func (r TableRecord) Columns() []string {
+	return []string{"name", "accessFrequency", "queryTime", "caching", "ttl", "rule"}
+}
+
+// Values flattens the record into one value per column, the rule is summarized.
+func (r TableRecord) Values() []string {
+	return []string{
+		r.Name,
+		strconv.FormatUint(r.AccessFrequency, 10),
+		strconv.FormatFloat(r.QueryTime, 'f', -1, 64),
+		string(r.Caching),
+		r.Ttl,
+		ruleSummaryOrEmptyString(r.Rule),
+	}
+}
+
+// RuleRecord is a rule as it is written in machine-readable output, together with its precedence, type and whether
+// it enables or disables caching.
+type RuleRecord struct {
+	Index   int     `json:"index" yaml:"index"`
+	Type    string  `json:"type" yaml:"type"`
+	Caching Caching `json:"caching" yaml:"caching"`
+	Rule    `yaml:",inline"`
+}
+
+func (r Rule) Record(index int) RuleRecord {
+	return RuleRecord{Index: index, Type: string(r.GetType()), Caching: RuleCaching(&r), Rule: r}
+}
+
+// Columns are the names of the fields Values returns, the header of CSV and TSV output.
+func (r RuleRecord) Columns() []string {
+	return []string{"index", "type", "caching", "ttl", "tables", "tablesAny", "tablesAll", "regex", "queryIds"}
+}
+
+// Values flattens the record into one value per column, lists are comma-delimited.
+func (r RuleRecord) Values() []string {
+	regex := ""
+	if r.Regex != nil {
+		regex = *r.Regex
+	}
+	return []string{
+		strconv.Itoa(r.Index),
+		r.Type,
+		string(r.Caching),
+		r.Ttl,
+		strings.Join(r.Tables, ","),
+		strings.Join(r.TablesAny, ","),
+		strings.Join(r.TablesAll, ","),
+		regex,
+		strings.Join(r.QueryIds, ","),
+	}
+}
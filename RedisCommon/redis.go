@@ -7,12 +7,12 @@ import (
 	"fmt"
 	"hash/fnv"
 	"reflect"
-	"regexp"
+	"smart-cache-cli/RuleMatcher"
 	"smart-cache-cli/SortDialog"
 	"smart-cache-cli/util"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/evertras/bubble-table/table"
@@ -33,6 +33,7 @@ const (
 	TablesAny RuleType = "Tables Any"
 	TablesAll RuleType = "Tables All"
 	QueryIds  RuleType = "Query IDs"
+	Composite RuleType = "Composite"
 	Unknown   RuleType = "Unknown"
 )
 
@@ -56,67 +57,70 @@ type Table struct {
 	AccessFrequency uint64
 	QueryTime       float64
 	Rule            *Rule
+	// RuleIndex is the index of Rule among the rules the table was matched against, -1 if no rule applies.
+	RuleIndex int
 }
 
 type Query struct {
-	Id          string
-	Table       string
-	Sql         string
-	Key         string
-	Count       int
-	MeanTime    float64
-	Selected    bool
-	Rule        *Rule
+	Id       string
+	Table    string
+	Sql      string
+	Key      string
+	Count    int
+	MeanTime float64
+	Selected bool
+	Rule     *Rule
+	// RuleIndex is the index of Rule among the rules the query was matched against, -1 if no rule applies.
+	RuleIndex   int
 	PendingRule *Rule
 }
 
 func (t Table) GetTtl() string {
 	if t.Rule != nil {
-		return t.Rule.Ttl
+		return DisplayTtl(t.Rule.Ttl)
 	}
 	return ""
 }
 
-func MatchTableAndRule(table Table, rules []Rule) *Rule {
-	for _, rule := range rules {
-		if rule.TablesAny != nil {
-			if contains(rule.TablesAny, table.Name) {
-				return &rule
-			}
-		}
-
-		if rule.TablesAll == nil && rule.Tables == nil && rule.TablesAny == nil && rule.Regex == nil && rule.QueryIds == nil {
-			return &rule
-		}
-
-		if rule.Tables != nil && contains(rule.Tables, table.Name) {
-			return &rule
-		}
-
-		if rule.TablesAll != nil && contains(rule.TablesAll, table.Name) {
-			return &rule
-		}
+// MatchTableRule sets the Rule and RuleIndex of the table to the rule that applies to queries on only that table. Rules
+// that depend on the SQL or the ID of a query are skipped since they vary between the queries on the table.
+func MatchTableRule(table *Table, rules []Rule) {
+	table.Rule = nil
+	table.RuleIndex = RuleMatcher.FirstTableMatch(ruleConditions(rules), table.Name)
+	if table.RuleIndex >= 0 {
+		rule := rules[table.RuleIndex]
+		table.Rule = &rule
 	}
-	return nil
 }
 
-func GetTables(rdb *redis.Client, applicationName string) []Table {
+func GetTables(rdb redis.UniversalClient, applicationName string) ([]Table, error) {
 	res, err := rdb.Do(ctx, "FT.AGGREGATE", fmt.Sprintf("%s-query-idx", applicationName), "*", "APPLY", "split(@table, ',')", "AS", "name", "GROUPBY", "1", "@name", "REDUCE", "SUM", "1", "count", "as", "accessFrequency", "REDUCE", "AVG", "1", "mean", "AS", "avgQueryTime").Result()
 
 	if err != nil {
-		panic(err)
+		return nil, wrapCommandError(err, "FT.AGGREGATE", "RediSearch")
 	}
 
 	rules, err := GetRules(rdb, applicationName)
 
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	outerArr := res.([]interface{})
-	tables := make([]Table, outerArr[0].(int64))
+
+	outerArr, ok := res.([]interface{})
+	if !ok || len(outerArr) < 1 {
+		return nil, unexpectedReply("FT.AGGREGATE", res)
+	}
+
+	tables := make([]Table, len(outerArr)-1)
 	for i, item := range outerArr[1:] {
-		innerArr := item.([]interface{})
-		dict := ToMap(innerArr)
+		innerArr, ok := item.([]interface{})
+		if !ok {
+			return nil, unexpectedReply("FT.AGGREGATE", item)
+		}
+		dict, err := ToMap(innerArr)
+		if err != nil {
+			return nil, err
+		}
 		name, _ := dict["name"]
 		accessFrequencyStr, _ := dict["accessFrequency"]
 		accessFrequency, _ := strconv.ParseUint(accessFrequencyStr, 10, 64)
@@ -129,25 +133,45 @@ func GetTables(rdb *redis.Client, applicationName string) []Table {
 		}
 	}
 
-	for i, table := range tables {
-		tables[i].Rule = MatchTableAndRule(table, rules)
+	for i := range tables {
+		MatchTableRule(&tables[i], rules)
 	}
 
-	return tables
+	return tables, nil
 }
 
 func GetPendingOrEmptyString(query *Query) string {
 	if query.PendingRule == nil {
 		return ""
 	}
-	return query.PendingRule.Ttl
+	return DisplayTtl(query.PendingRule.Ttl)
 }
 
 func GetTtlOrEmptyString(query *Query) string {
 	if query.Rule == nil {
 		return ""
 	}
-	return query.Rule.Ttl
+	return DisplayTtl(query.Rule.Ttl)
+}
+
+// ruleTtlOrEmptyString is the TTL of a rule as it is stored, for sorting by.
+func ruleTtlOrEmptyString(rule *Rule) string {
+	if rule == nil {
+		return ""
+	}
+	return rule.Ttl
+}
+
+// CachingStatus is whether the results of the query are cached and, if they are not, why.
+func CachingStatus(query *Query) string {
+	switch RuleCaching(query.Rule) {
+	case CachingUnset:
+		return "FALSE (no matching rule)"
+	case CachingOff:
+		return "FALSE (disabled by rule)"
+	default:
+		return "TRUE"
+	}
 }
 
 func makeColumn(key string, title string, columnWidth int) table.Column {
@@ -171,6 +195,22 @@ func GetColumnNames() []string {
 	}
 }
 
+// ttlSortKeys maps the columns that show a TTL to the hidden row data they sort by, so that they sort by duration
+// rather than alphabetically.
+var ttlSortKeys = map[string]string{
+	"TTL":          "TTL Duration",
+	"Current ttl":  "Current ttl Duration",
+	"Pending Rule": "Pending Rule Duration",
+}
+
+// SortKey returns the row data a table sorts by when sorted on column.
+func SortKey(column string) string {
+	if key, ok := ttlSortKeys[column]; ok {
+		return key
+	}
+	return column
+}
+
 func CreateColumns(sortColumn string, direction SortDialog.Direction, colNames []string, colWidth int) []table.Column {
 	columns := make(map[string]table.Column)
 
@@ -219,7 +259,8 @@ func GetColumnsOfRule(sortColumn string, direction SortDialog.Direction) []table
 func GetColumnsOfQuery(sortColumn string, direction SortDialog.Direction) []table.Column {
 
 	colNames := []string{
-		"Id", "Pending Rule", "Key", "Table", "Sql", "Access Frequency", "Mean Query Time", "Caching Enabled", "Current ttl",
+		"Id", "Pending Rule", "Key", "Table", "Sql", "Access Frequency", "Mean Query Time", "Last Change", "Caching Enabled",
+		"Current ttl",
 	}
 
 	return CreateColumns(sortColumn, direction, colNames, 20)
@@ -242,32 +283,34 @@ func (t *Table) GetAsRow(rowId int) table.Row {
 		"Query Time":       fmt.Sprintf("%.2f", t.QueryTime),
 		"Access Frequency": t.AccessFrequency,
 		"TTL":              t.GetTtl(),
+		"TTL Duration":     TtlSortValue(ruleTtlOrEmptyString(t.Rule)),
 		"RowId":            rowId,
 	})
 }
 
 func (query *Query) GetAsRow(rowId int) table.Row {
-	cachingEnabled := "TRUE"
-	if GetTtlOrEmptyString(query) == "" {
-		cachingEnabled = "FALSE"
-	}
 	return table.NewRow(table.RowData{
-		"Id":               query.Id,
-		"Pending Rule":     GetPendingOrEmptyString(query),
-		"Key":              query.Key,
-		"Table":            query.Table,
-		"Sql":              query.Sql,
-		"Access Frequency": strconv.Itoa(query.Count),
-		"Mean Query Time":  fmt.Sprintf("%.2fms", query.MeanTime),
-		"Current ttl":      GetTtlOrEmptyString(query),
-		"RowId":            rowId,
-		"Caching Enabled":  cachingEnabled,
+		"Id":                    query.Id,
+		"Pending Rule":          GetPendingOrEmptyString(query),
+		"Pending Rule Duration": TtlSortValue(ruleTtlOrEmptyString(query.PendingRule)),
+		"Key":                   query.Key,
+		"Table":                 query.Table,
+		"Sql":                   query.Sql,
+		"Access Frequency":      strconv.Itoa(query.Count),
+		"Mean Query Time":       fmt.Sprintf("%.2fms", query.MeanTime),
+		"Current ttl":           GetTtlOrEmptyString(query),
+		"Current ttl Duration":  TtlSortValue(ruleTtlOrEmptyString(query.Rule)),
+		"RowId":                 rowId,
+		"Caching Enabled":       CachingStatus(query),
 	})
 }
 
 func (r Rule) Formatted() string {
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf("Rule Type:%s\nRule TTL:%s\n", r.GetType(), r.Ttl))
+	if r.DisablesCaching() {
+		builder.WriteString("Disables caching of the queries it matches\n")
+	}
 
 	if r.Tables != nil {
 		builder.WriteString(fmt.Sprintf("Tables: %s\n", strings.Join(r.Tables, ",")))
@@ -333,6 +376,7 @@ func (query *Query) Formatted(width int) string {
 	builder.WriteString(fmt.Sprintf("Access frequency %s\n", strconv.Itoa(query.Count)))
 	builder.WriteString(fmt.Sprintf("Mean query time: %.2fms\n", query.MeanTime))
 	builder.WriteString(fmt.Sprintf("Current TTL: %s\n", GetTtlOrEmptyString(query)))
+	builder.WriteString(fmt.Sprintf("Caching enabled: %s\n", CachingStatus(query)))
 
 	return builder.String()
 }
@@ -382,14 +426,13 @@ func GetHeader(colWidth int) string {
 	return row
 }
 
-func (r Rule) GetJson() string {
+func (r Rule) GetJson() (string, error) {
 	b, err := json.Marshal(r)
 	if err != nil {
-		fmt.Println("Error: Unable to serialize rule.")
-		panic(r)
+		return "", fmt.Errorf("unable to serialize rule: %w", err)
 	}
 
-	return string(b)
+	return string(b), nil
 }
 
 func (r Rule) Equal(other Rule) bool {
@@ -397,12 +440,12 @@ func (r Rule) Equal(other Rule) bool {
 }
 
 type Rule struct {
-	Tables    []string `json:"tables"`
-	TablesAny []string `json:"tablesAny"`
-	TablesAll []string `json:"tablesAll"`
-	Regex     *string  `json:"regex"`
-	QueryIds  []string `json:"queryIds"`
-	Ttl       string   `json:"ttl"`
+	Tables    []string `json:"tables,omitempty" yaml:"tables,omitempty"`
+	TablesAny []string `json:"tablesAny,omitempty" yaml:"tablesAny,omitempty"`
+	TablesAll []string `json:"tablesAll,omitempty" yaml:"tablesAll,omitempty"`
+	Regex     *string  `json:"regex,omitempty" yaml:"regex,omitempty"`
+	QueryIds  []string `json:"queryIds,omitempty" yaml:"queryIds,omitempty"`
+	Ttl       string   `json:"ttl" yaml:"ttl"`
 }
 
 func (r Rule) Type() {
@@ -415,43 +458,118 @@ type SearchResult struct {
 	indexType IndexType
 }
 
-func ToMap(res []interface{}) map[string]string {
+func ToMap(res []interface{}) (map[string]string, error) {
+	if len(res)%2 != 0 {
+		return nil, fmt.Errorf("%w: odd number of field-value elements", ErrUnexpectedReply)
+	}
+
 	m := make(map[string]string, len(res)/2)
 	for i := 0; i < len(res); i += 2 {
-		m[res[i].(string)] = res[i+1].(string)
+		field, fok := res[i].(string)
+		value, vok := res[i+1].(string)
+		if !fok || !vok {
+			return nil, fmt.Errorf("%w: expected string field-value pair, got %T and %T", ErrUnexpectedReply, res[i], res[i+1])
+		}
+		m[field] = value
 	}
-	return m
+	return m, nil
 }
 
-func ToLabelsMap(res []interface{}) map[string]string {
-	m := make(map[string]string, len(res)/2)
+func ToLabelsMap(res []interface{}) (map[string]string, error) {
+	m := make(map[string]string, len(res))
 	for _, item := range res {
-		fvp := item.([]interface{})
-		m[fvp[0].(string)] = fvp[1].(string)
+		fvp, ok := item.([]interface{})
+		if !ok || len(fvp) != 2 {
+			return nil, fmt.Errorf("%w: expected label pair, got %v", ErrUnexpectedReply, item)
+		}
+
+		label, lok := fvp[0].(string)
+		value, vok := fvp[1].(string)
+		if !lok || !vok {
+			return nil, fmt.Errorf("%w: expected string label pair, got %T and %T", ErrUnexpectedReply, fvp[0], fvp[1])
+		}
+		m[label] = value
 	}
-	return m
+	return m, nil
+}
+
+// sampleValue extracts the value of a TS.MGET sample, which is a [timestamp, value] pair, or an empty array
+// for a series with no samples yet.
+func sampleValue(sample interface{}) (string, error) {
+	pair, ok := sample.([]interface{})
+	if ok && len(pair) == 0 {
+		return "", nil
+	}
+
+	if !ok || len(pair) != 2 {
+		return "", unexpectedReply("TS.MGET", sample)
+	}
+
+	value, ok := pair[1].(string)
+	if !ok {
+		return "", unexpectedReply("TS.MGET", pair[1])
+	}
+
+	return value, nil
+}
+
+// forEachShard calls fn against every primary of a cluster, or once against the client itself for standalone
+// and sentinel topologies, so that commands such as TS.MGET see keys living on every shard.
+func forEachShard(rdb redis.UniversalClient, fn func(c redis.UniversalClient) error) error {
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return fn(client)
+		})
+	}
+
+	return fn(rdb)
 }
 
-func GetQueries(rdb *redis.Client, applicationName string) ([]*Query, error) {
-	res, err := rdb.Do(ctx, "TS.MGET", "WITHLABELS", "FILTER", "name=query", "stat=(count,mean)").Result()
+func GetQueries(rdb redis.UniversalClient, applicationName string) ([]*Query, error) {
+	var mu sync.Mutex
+	arr := make([]interface{}, 0)
+	err := forEachShard(rdb, func(c redis.UniversalClient) error {
+		res, err := c.Do(ctx, "TS.MGET", "WITHLABELS", "FILTER", "name=query", "stat=(count,mean)").Result()
+		if err != nil {
+			return wrapCommandError(err, "TS.MGET", "RedisTimeSeries")
+		}
+
+		shardArr, ok := res.([]interface{})
+		if !ok {
+			return unexpectedReply("TS.MGET", res)
+		}
+
+		mu.Lock()
+		arr = append(arr, shardArr...)
+		mu.Unlock()
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+
 	rules, err := GetRules(rdb, applicationName)
 	if err != nil {
 		return nil, err
 	}
 
-	arr, ok := res.([]interface{})
-	if !ok {
-		return nil, errors.New("Error: Failed to parse result from Redis")
-	}
-
 	queries := make(map[string]*Query)
 
 	for _, item := range arr {
-		labelArr := item.([]interface{})[1]
-		labels := ToLabelsMap(labelArr.([]interface{}))
+		series, ok := item.([]interface{})
+		if !ok || len(series) != 3 {
+			return nil, unexpectedReply("TS.MGET", item)
+		}
+
+		labelArr, ok := series[1].([]interface{})
+		if !ok {
+			return nil, unexpectedReply("TS.MGET", series[1])
+		}
+
+		labels, err := ToLabelsMap(labelArr)
+		if err != nil {
+			return nil, err
+		}
 		id := labels["id"]
 
 		_, exists := queries[id]
@@ -464,20 +582,39 @@ func GetQueries(rdb *redis.Client, applicationName string) ([]*Query, error) {
 		}
 
 		if labels["stat"] == "mean" {
-			queries[id].MeanTime, err = strconv.ParseFloat(item.([]interface{})[2].([]interface{})[1].(string), 64)
+			value, err := sampleValue(series[2])
 			if err != nil {
 				return nil, err
 			}
+
+			if value == "" {
+				continue
+			}
+
+			queries[id].MeanTime, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid mean '%s' for query %s", ErrUnexpectedReply, value, id)
+			}
 		}
 
 		if labels["stat"] == "count" {
-			queries[id].Count, err = strconv.Atoi(item.([]interface{})[2].([]interface{})[1].(string))
+			value, err := sampleValue(series[2])
 			if err != nil {
 				return nil, err
 			}
+
+			if value == "" {
+				continue
+			}
+
+			queries[id].Count, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid count '%s' for query %s", ErrUnexpectedReply, value, id)
+			}
 		}
 	}
 
+	// in cluster mode the pipeline splits the HGETALLs by hash slot and sends each batch to the owning shard
 	pipeResults := make(map[string]*redis.MapStringStringCmd)
 	pipe := rdb.Pipeline()
 	for id := range queries {
@@ -527,192 +664,163 @@ func contains(s []string, str string) bool {
 	return false
 }
 
-func (r Rule) GetType() RuleType {
+// ConditionTypes lists the conditions a rule sets, in the order Smart Cache evaluates them.
+func (r Rule) ConditionTypes() []RuleType {
+	types := make([]RuleType, 0)
 	if r.Tables != nil {
-		return Tables
+		types = append(types, Tables)
 	}
-
 	if r.TablesAny != nil {
-		return TablesAny
+		types = append(types, TablesAny)
 	}
-
 	if r.TablesAll != nil {
-		return TablesAll
+		types = append(types, TablesAll)
+	}
+	if r.Regex != nil {
+		types = append(types, Regex)
 	}
-
 	if r.QueryIds != nil {
-		return QueryIds
+		types = append(types, QueryIds)
 	}
+	return types
+}
 
-	if r.Regex != nil {
-		return Regex
+// ConditionValue renders the value of one of the conditions of a rule, a list as comma-delimited values.
+func (r Rule) ConditionValue(ruleType RuleType) string {
+	switch ruleType {
+	case Tables:
+		return strings.Join(r.Tables, ",")
+	case TablesAny:
+		return strings.Join(r.TablesAny, ",")
+	case TablesAll:
+		return strings.Join(r.TablesAll, ",")
+	case QueryIds:
+		return strings.Join(r.QueryIds, ",")
+	case Regex:
+		if r.Regex != nil {
+			return *r.Regex
+		}
 	}
-
-	return All
+	return ""
 }
 
-func (r Rule) AsRow(rowId int) table.Row {
-
-	rd := table.RowData{}
-	rd["TTL"] = r.Ttl
-	rd["Matches"] = "any"
-	rd["Rule Type"] = r.GetType()
-	if r.Tables != nil {
-		rd["Matches"] = strings.Join(r.Tables, ",")
+// GetType returns the condition a rule sets, All if it sets none, or Composite if it combines several.
+func (r Rule) GetType() RuleType {
+	types := r.ConditionTypes()
+	switch len(types) {
+	case 0:
+		return All
+	case 1:
+		return types[0]
+	default:
+		return Composite
 	}
+}
 
-	if r.TablesAny != nil {
-		rd["Matches"] = strings.Join(r.TablesAny, ",")
+// Matches describes what a rule matches on: the value of its condition, or each condition prefixed by its type if it
+// combines several.
+func (r Rule) Matches() string {
+	types := r.ConditionTypes()
+	switch len(types) {
+	case 0:
+		return "any"
+	case 1:
+		return r.ConditionValue(types[0])
 	}
 
-	if r.TablesAll != nil {
-		rd["Matches"] = strings.Join(r.TablesAll, ",")
+	conditions := make([]string, len(types))
+	for i, t := range types {
+		conditions[i] = fmt.Sprintf("%s: %s", t, r.ConditionValue(t))
 	}
+	return strings.Join(conditions, " AND ")
+}
 
-	if r.QueryIds != nil {
-		rd["Matches"] = strings.Join(r.QueryIds, ",")
-	}
+// DisablesCaching reports whether this is a no-cache rule, which keeps the queries it matches from being cached
+// instead of caching them. A no-cache rule has a TTL of zero.
+func (r Rule) DisablesCaching() bool {
+	t, err := ParseTtl(r.Ttl)
+	return err == nil && t == NoCache
+}
 
-	if r.Regex != nil {
-		rd["Matches"] = *r.Regex
-	}
+func (r Rule) AsRow(rowId int) table.Row {
 
+	rd := table.RowData{}
+	rd["TTL"] = DisplayTtl(r.Ttl)
+	rd["TTL Duration"] = TtlSortValue(r.Ttl)
+	rd["Matches"] = r.Matches()
+	rd["Rule Type"] = r.GetType()
 	rd["RowId"] = rowId
 
 	return table.NewRow(rd)
 }
 
-func GetRules(rdb *redis.Client, applicationName string) ([]Rule, error) {
+// RuleSet is the ordered rule set of a config stream entry together with the ID of that entry. The ID is empty when
+// no rules have been configured yet.
+type RuleSet struct {
+	Id    string
+	Rules []Rule
+}
+
+func GetRuleSet(rdb redis.UniversalClient, applicationName string) (RuleSet, error) {
 
 	res, err := rdb.XRevRangeN(ctx, fmt.Sprintf("%s:config", applicationName), "+", "-", 1).Result()
 
 	if err != nil {
-		return nil, err
+		return RuleSet{}, err
 	}
 
 	if len(res) < 1 {
-		return make([]Rule, 0), nil
+		return RuleSet{Rules: make([]Rule, 0)}, nil
 	}
 
-	ruleMap := make(map[int]Rule)
-
-	for key, _ := range res[0].Values {
-		value := res[0].Values[key]
-		split := strings.Split(key, ".")
-		if len(split) < 3 {
-			fmt.Printf("Skipping invalid rule '%s'\n", res[0].Values[key])
-			continue
-		}
-
-		ruleNum, err := strconv.Atoi(split[1])
-		if err != nil {
-			fmt.Printf("Skipping rule '%s'. Invalid rule number %s\n", key)
-			continue
-		}
-
-		rule, ruleInMap := ruleMap[ruleNum]
-		if !ruleInMap {
-			rule = Rule{}
-		}
-
-		ruleComponent := split[2]
+	rules, err := ParseRules(res[0].ID, res[0].Values)
+	if err != nil {
+		return RuleSet{}, err
+	}
 
-		switch ruleComponent {
-		case "tables":
-			if rule.Tables == nil {
-				rule.Tables = make([]string, 0)
-			}
-			rule.Tables = append(rule.Tables, value.(string))
-		case "tables-any":
-			if rule.TablesAny == nil {
-				rule.TablesAny = make([]string, 0)
-			}
-			rule.TablesAny = append(rule.TablesAny, value.(string))
-		case "tables-all":
-			if rule.TablesAll == nil {
-				rule.TablesAll = make([]string, 0)
-			}
-			rule.TablesAll = append(rule.TablesAll, value.(string))
-		case "query-ids":
-			if rule.QueryIds == nil {
-				rule.QueryIds = make([]string, 0)
-			}
-			rule.QueryIds = append(rule.QueryIds, value.(string))
-		case "regex":
-			r := value.(string)
-			rule.Regex = &r
-		case "ttl":
-			rule.Ttl = value.(string)
-		}
+	return RuleSet{Id: res[0].ID, Rules: rules}, nil
+}
 
-		ruleMap[ruleNum] = rule
+func GetRules(rdb redis.UniversalClient, applicationName string) ([]Rule, error) {
+	ruleSet, err := GetRuleSet(rdb, applicationName)
+	return ruleSet.Rules, err
+}
 
+// Conditions returns the match conditions of the rule for evaluation with RuleMatcher.
+func (r Rule) Conditions() RuleMatcher.Conditions {
+	return RuleMatcher.Conditions{
+		Tables:    r.Tables,
+		TablesAny: r.TablesAny,
+		TablesAll: r.TablesAll,
+		Regex:     r.Regex,
+		QueryIds:  r.QueryIds,
 	}
+}
 
-	rules := make([]Rule, len(ruleMap))
-
-	for i, rule := range ruleMap {
-		rules[i-1] = rule
+func ruleConditions(rules []Rule) []RuleMatcher.Conditions {
+	conditions := make([]RuleMatcher.Conditions, len(rules))
+	for i, r := range rules {
+		conditions[i] = r.Conditions()
 	}
+	return conditions
+}
 
-	return rules, nil
+// Subject returns the query as RuleMatcher evaluates rules against it.
+func (query *Query) Subject() RuleMatcher.Subject {
+	return RuleMatcher.Subject{
+		Id:     query.Id,
+		Sql:    query.Sql,
+		Tables: RuleMatcher.ParseTables(query.Table),
+	}
 }
 
+// MatchRule sets the rule of the query to the first rule that matches it, see RuleMatcher for the semantics.
 func MatchRule(query *Query, rules []Rule) {
-	tables := strings.Split(query.Table, ",")
-	for _, rule := range rules {
-		match := true
-		for _, table := range tables {
-			match = match && contains(rule.Tables, table)
-		}
-
-		if match {
-			query.Rule = &rule
-			return
-		}
-
-		match = true
-		for _, table := range rule.TablesAll {
-			match = match && contains(tables, table)
-		}
-
-		if match && rule.TablesAll != nil && len(rule.TablesAll) > 0 {
-			query.Rule = &rule
-			return
-		}
-
-		match = false
-		for _, table := range tables {
-			match = match || contains(rule.TablesAny, table)
-		}
-
-		if match {
-			query.Rule = &rule
-			return
-		}
-
-		if rule.Regex != nil {
-			match, err := regexp.MatchString(*rule.Regex, query.Sql)
-			if err != nil {
-				match = false
-			}
-
-			if match {
-				query.Rule = &rule
-				return
-			}
-
-		}
-
-		if contains(rule.QueryIds, query.Id) {
-			query.Rule = &rule
-			return
-		}
-
-		if rule.TablesAny == nil && rule.Tables == nil && rule.TablesAll == nil && rule.Regex == nil && rule.QueryIds == nil {
-			query.Rule = &rule
-			return
-		}
+	query.Rule = nil
+	query.RuleIndex = RuleMatcher.FirstMatch(ruleConditions(rules), query.Subject())
+	if query.RuleIndex >= 0 {
+		rule := rules[query.RuleIndex]
+		query.Rule = &rule
 	}
 }
 
@@ -777,133 +885,209 @@ func serializeToJacksonArr(arr []string, component string, ruleNum int) []string
 
 func (r Rule) SerializeToStreamMsg(ruleNum int) []string {
 	var ret []string
-	ret = append(ret, fmt.Sprintf("rules.%d.ttl", ruleNum))
+	ret = append(ret, fmt.Sprintf("rules.%d.%s", ruleNum, ttlComponent))
 	ret = append(ret, r.Ttl)
 	if r.Regex != nil {
-		ret = append(ret, fmt.Sprintf("rules.%d.regex", ruleNum))
+		ret = append(ret, fmt.Sprintf("rules.%d.%s", ruleNum, regexComponent))
 		ret = append(ret, *r.Regex)
 	}
 	if r.TablesAny != nil {
-		ret = append(ret, serializeToJacksonArr(r.TablesAny, "tables-any", ruleNum)...)
+		ret = append(ret, serializeToJacksonArr(r.TablesAny, tablesAnyComponent, ruleNum)...)
 	}
 	if r.Tables != nil {
-		ret = append(ret, serializeToJacksonArr(r.Tables, "tables", ruleNum)...)
+		ret = append(ret, serializeToJacksonArr(r.Tables, tablesComponent, ruleNum)...)
 	}
 	if r.TablesAll != nil {
-		ret = append(ret, serializeToJacksonArr(r.TablesAll, "tables-all", ruleNum)...)
+		ret = append(ret, serializeToJacksonArr(r.TablesAll, tablesAllComponent, ruleNum)...)
 	}
 	if r.QueryIds != nil {
-		ret = append(ret, serializeToJacksonArr(r.QueryIds, "query-ids", ruleNum)...)
+		ret = append(ret, serializeToJacksonArr(r.QueryIds, queryIdsComponent, ruleNum)...)
 	}
 
 	return ret
 }
 
-func CommitNewRules(rdb *redis.Client, rules []Rule, applicationName string) (string, error) {
-	currentRules, err := GetRules(rdb, applicationName)
-	if err != nil {
-		panic(err)
+// serializeRules flattens an ordered rule set into the field-value pairs of a config stream entry. An empty rule
+// set is written as the emptyRulesKey marker, see there why Smart Cache reads it as no rules. Rules with an empty
+// list condition are rejected, as the stream has no way to tell an empty list from a missing one.
+func serializeRules(rules []Rule) ([]string, error) {
+	if err := checkRulesSerializable(rules); err != nil {
+		return nil, err
 	}
 
 	args := make([]string, 0)
-
 	for i, rule := range rules {
 		args = append(args, rule.SerializeToStreamMsg(i+1)...)
 	}
 
-	for i, rule := range currentRules {
-		args = append(args, rule.SerializeToStreamMsg(i+1+len(rules))...)
+	if len(args) == 0 {
+		args = append(args, emptyRulesKey)
+		args = append(args, emptyRulesValue)
 	}
 
-	xAddArgs := redis.XAddArgs{Stream: fmt.Sprintf("%s:config", applicationName), Values: args}
+	return args, nil
+}
 
-	id, err := rdb.XAdd(ctx, &xAddArgs).Result()
-	if err != nil {
-		return "", err
+// checkRulesSerializable returns an error, naming the rule by its precedence, if a rule of the rule set cannot be
+// stored in the config stream.
+func checkRulesSerializable(rules []Rule) error {
+	for i, rule := range rules {
+		if err := checkSerializable(rule); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
 	}
-
-	return id, nil
+	return nil
 }
 
-func UpdateRules(rdb *redis.Client, rulesToAdd []Rule, rulesToUpdate map[int]Rule, rulesToDelete map[int]Rule, applicationName string) error {
-	currentRules, err := GetRules(rdb, applicationName)
+// checkSerializable returns an error if a condition of the rule is an empty list.
+func checkSerializable(r Rule) error {
+	lists := []struct {
+		component string
+		values    []string
+	}{
+		{tablesComponent, r.Tables},
+		{tablesAnyComponent, r.TablesAny},
+		{tablesAllComponent, r.TablesAll},
+		{queryIdsComponent, r.QueryIds},
+	}
+	for _, l := range lists {
+		if l.values != nil && len(l.values) == 0 {
+			return fmt.Errorf("the %s condition is an empty list, which cannot be stored in the config stream", l.component)
+		}
+	}
+	return nil
+}
 
+// commitRulesScript appends a new config entry only if the head of the stream is still the entry the rules were
+// loaded from, making the read-modify-write of a rule update atomic.
+var commitRulesScript = redis.NewScript(`
+local head = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+local current = ''
+if #head > 0 then
+	current = head[1][1]
+end
+if current ~= ARGV[1] then
+	return redis.error_reply('STALE ' .. current)
+end
+return redis.call('XADD', KEYS[1], '*', unpack(ARGV, 2))
+`)
+
+// CommitRuleSet publishes rules as the new head of the config stream, provided that baseId, the entry the rules
+// were derived from, is still the head. Otherwise it returns a *StaleRulesError.
+func CommitRuleSet(rdb redis.UniversalClient, baseId string, rules []Rule, applicationName string) (string, error) {
+	fields, err := serializeRules(rules)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
-	rulesToCommit := make([]Rule, len(currentRules))
-	copy(rulesToCommit, currentRules)
-
-	for index, rule := range rulesToUpdate {
-		if index >= len(currentRules) {
-			return fmt.Errorf("Unable to update rules: rules out of sync.")
-		}
+	args := make([]interface{}, 0, len(fields)+1)
+	args = append(args, baseId)
+	for _, f := range fields {
+		args = append(args, f)
+	}
 
-		rulesToCommit[index] = rule
+	res, err := commitRulesScript.Run(ctx, rdb, []string{fmt.Sprintf("%s:config", applicationName)}, args...).Result()
+	if err != nil {
+		return "", staleRulesError(baseId, err)
 	}
 
-	indexesToPop := make([]int, len(rulesToDelete))
-	i := 0
-	for index, _ := range rulesToDelete {
-		indexesToPop[i] = index
-		i++
+	id, ok := res.(string)
+	if !ok {
+		return "", unexpectedReply("XADD", res)
 	}
 
-	sort.Slice(indexesToPop, func(i, j int) bool {
-		return indexesToPop[i] > indexesToPop[j]
-	})
+	return id, nil
+}
 
-	for _, i := range indexesToPop {
-		rulesToCommit = append(rulesToCommit[:i], rulesToCommit[i+1:]...)
+// staleRulesError turns the STALE reply of commitRulesScript into a *StaleRulesError and returns other errors as is.
+func staleRulesError(baseId string, err error) error {
+	if currentId, stale := strings.CutPrefix(err.Error(), "STALE"); stale {
+		return &StaleRulesError{ExpectedId: baseId, CurrentId: strings.TrimSpace(currentId)}
 	}
+	return err
+}
 
-	for _, rule := range rulesToAdd {
-		rulesToCommit = append([]Rule{rule}, rulesToCommit...)
-	}
+// maxCommitAttempts bounds how often CommitNewRules retries when it races with another commit. Inserting new rules at
+// a position does not depend on the other rules, so it is safe to re-apply on top of the latest entry.
+const maxCommitAttempts = 3
 
-	args := make([]string, 0)
+// CommitNewRules inserts rules at position among the current rules.
+func CommitNewRules(rdb redis.UniversalClient, rules []Rule, position RulePosition, applicationName string) (string, error) {
+	var err error
+	for attempt := 0; attempt < maxCommitAttempts; attempt++ {
+		var current RuleSet
+		current, err = GetRuleSet(rdb, applicationName)
+		if err != nil {
+			return "", err
+		}
 
-	for i, rule := range rulesToCommit {
-		args = append(args, rule.SerializeToStreamMsg(i+1)...)
+		var proposed []Rule
+		proposed, err = insertRules(rules, current.Rules, position)
+		if err != nil {
+			return "", err
+		}
+
+		var id string
+		id, err = CommitRuleSet(rdb, current.Id, proposed, applicationName)
+		if !errors.Is(err, ErrRulesOutOfSync) {
+			return id, err
+		}
 	}
 
-	if len(args) == 0 {
-		args = append(args, "rule.1.ttl")
-		args = append(args, "0s")
+	return "", err
+}
+
+// UpdateRules commits order, the full ordering of the rules edited from the rule set loaded from config entry baseId.
+// Rules of that set that order does not list are deleted. If the config has changed since baseId was loaded it
+// returns a *StaleRulesError instead of overwriting the other change.
+func UpdateRules(rdb redis.UniversalClient, baseId string, order []OrderedRule, applicationName string) error {
+	current, err := GetRuleSet(rdb, applicationName)
+
+	if err != nil {
+		return err
 	}
 
-	xAddArgs := redis.XAddArgs{Stream: fmt.Sprintf("%s:config", applicationName), Values: args}
+	if current.Id != baseId {
+		return &StaleRulesError{ExpectedId: baseId, CurrentId: current.Id}
+	}
 
-	_, err = rdb.XAdd(ctx, &xAddArgs).Result()
+	rulesToCommit, err := applyRuleOrder(current.Rules, order)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	_, err = CommitRuleSet(rdb, baseId, rulesToCommit, applicationName)
+	return err
 }
 
-func Ping(rdb *redis.Client) error {
+func Ping(rdb redis.UniversalClient) error {
 	_, err := rdb.Ping(ctx).Result()
 	return err
 }
 
-func CheckSmartCacheIndex(rdb *redis.Client, applicationName string) error {
+func CheckSmartCacheIndex(rdb redis.UniversalClient, applicationName string) error {
 	res, err := rdb.Do(ctx, "FT._LIST").Result()
 	if err != nil {
-		return err
+		return wrapCommandError(err, "FT._LIST", "RediSearch")
 	}
 
-	arr := res.([]interface{})
+	arr, ok := res.([]interface{})
+	if !ok {
+		return unexpectedReply("FT._LIST", res)
+	}
 
 	strs := make([]string, len(arr))
 
 	for index, i := range arr {
-		strs[index] = i.(string)
+		strs[index], ok = i.(string)
+		if !ok {
+			return unexpectedReply("FT._LIST", i)
+		}
 	}
 
 	if !contains(strs, fmt.Sprintf("%s-query-idx", applicationName)) {
-		return errors.New(fmt.Sprintf("Redis Smart Cache does not appear to be configured for application '%s'. "+
-			"Please ensure that Redis Smart Cache is running, configured with application '%s', and pointed at the correct Redis instance.", applicationName, applicationName))
+		return fmt.Errorf("%w: Redis Smart Cache does not appear to be configured for application '%s'. "+
+			"Please ensure that Redis Smart Cache is running, configured with application '%s', and pointed at the correct Redis instance.", ErrIndexMissing, applicationName, applicationName)
 	}
 
 	return nil
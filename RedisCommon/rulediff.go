@@ -0,0 +1,98 @@
+package RedisCommon
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	Unchanged ChangeKind = " "
+	Added     ChangeKind = "+"
+	Removed   ChangeKind = "-"
+)
+
+// RuleChange is one line of a rule-level diff. OldIndex and NewIndex are -1 for added and removed rules respectively.
+type RuleChange struct {
+	Kind     ChangeKind
+	Rule     Rule
+	OldIndex int
+	NewIndex int
+}
+
+// DiffRules computes an ordered rule-level diff between two rule sets using their longest common subsequence, so
+// that a rule moved in precedence shows up as removed from its old position and added at its new one.
+func DiffRules(oldRules []Rule, newRules []Rule) []RuleChange {
+	n, m := len(oldRules), len(newRules)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldRules[i].Equal(newRules[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	changes := make([]RuleChange, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		if oldRules[i].Equal(newRules[j]) {
+			changes = append(changes, RuleChange{Kind: Unchanged, Rule: newRules[j], OldIndex: i, NewIndex: j})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			changes = append(changes, RuleChange{Kind: Removed, Rule: oldRules[i], OldIndex: i, NewIndex: -1})
+			i++
+		} else {
+			changes = append(changes, RuleChange{Kind: Added, Rule: newRules[j], OldIndex: -1, NewIndex: j})
+			j++
+		}
+	}
+
+	for ; i < n; i++ {
+		changes = append(changes, RuleChange{Kind: Removed, Rule: oldRules[i], OldIndex: i, NewIndex: -1})
+	}
+
+	for ; j < m; j++ {
+		changes = append(changes, RuleChange{Kind: Added, Rule: newRules[j], OldIndex: -1, NewIndex: j})
+	}
+
+	return changes
+}
+
+// HasChanges reports whether a diff contains anything other than unchanged rules.
+func HasChanges(changes []RuleChange) bool {
+	for _, c := range changes {
+		if c.Kind != Unchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes a rule on a single line, e.g. "5m Tables Any [orders,customers]".
+func (r Rule) Summary() string {
+	return fmt.Sprintf("%s %s [%s]", r.Ttl, r.GetType(), r.Matches())
+}
+
+// FormatRuleDiff renders a diff with one line per rule, prefixed by +, - or a space and numbered by precedence.
+func FormatRuleDiff(changes []RuleChange) string {
+	b := strings.Builder{}
+	for _, c := range changes {
+		index := c.NewIndex
+		if c.Kind == Removed {
+			index = c.OldIndex
+		}
+		b.WriteString(fmt.Sprintf("%s %3d. %s\n", c.Kind, index, c.Rule.Summary()))
+	}
+	return b.String()
+}
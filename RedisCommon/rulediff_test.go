@@ -0,0 +1,94 @@
+package RedisCommon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRules(t *testing.T) {
+	a, b, c, x := tableRule("a"), tableRule("b"), tableRule("c"), tableRule("x")
+	longerB := Rule{TablesAny: []string{"b"}, Ttl: "1h"}
+
+	tests := []struct {
+		name        string
+		old         []Rule
+		new         []Rule
+		want        []RuleChange
+		wantChanges bool
+	}{
+		{
+			name: "identical",
+			old:  []Rule{a, b},
+			new:  []Rule{a, b},
+			want: []RuleChange{{Unchanged, a, 0, 0}, {Unchanged, b, 1, 1}},
+		},
+		{
+			name:        "pure insert",
+			old:         []Rule{a, b},
+			new:         []Rule{a, x, b},
+			want:        []RuleChange{{Unchanged, a, 0, 0}, {Added, x, -1, 1}, {Unchanged, b, 1, 2}},
+			wantChanges: true,
+		},
+		{
+			name:        "pure delete",
+			old:         []Rule{a, b, c},
+			new:         []Rule{a, c},
+			want:        []RuleChange{{Unchanged, a, 0, 0}, {Removed, b, 1, -1}, {Unchanged, c, 2, 1}},
+			wantChanges: true,
+		},
+		{
+			name:        "changed rule",
+			old:         []Rule{a, b, c},
+			new:         []Rule{a, longerB, c},
+			want:        []RuleChange{{Unchanged, a, 0, 0}, {Removed, b, 1, -1}, {Added, longerB, -1, 1}, {Unchanged, c, 2, 2}},
+			wantChanges: true,
+		},
+		{
+			name:        "reordered rule",
+			old:         []Rule{a, b, c},
+			new:         []Rule{c, a, b},
+			want:        []RuleChange{{Added, c, -1, 0}, {Unchanged, a, 0, 1}, {Unchanged, b, 1, 2}, {Removed, c, 2, -1}},
+			wantChanges: true,
+		},
+		{
+			name:        "from no rules",
+			old:         []Rule{},
+			new:         []Rule{a},
+			want:        []RuleChange{{Added, a, -1, 0}},
+			wantChanges: true,
+		},
+		{
+			name:        "to no rules",
+			old:         []Rule{a},
+			new:         []Rule{},
+			want:        []RuleChange{{Removed, a, 0, -1}},
+			wantChanges: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffRules(tt.old, tt.new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffRules() = %v, want %v", got, tt.want)
+			}
+			if changes := HasChanges(got); changes != tt.wantChanges {
+				t.Errorf("HasChanges() = %v, want %v", changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestFormatRuleDiff(t *testing.T) {
+	changes := DiffRules(
+		[]Rule{tableRule("a"), tableRule("b")},
+		[]Rule{tableRule("a"), {TablesAny: []string{"b"}, Ttl: "1h"}},
+	)
+
+	want := "    0. 5m Tables Any [a]\n" +
+		"-   1. 5m Tables Any [b]\n" +
+		"+   1. 1h Tables Any [b]\n"
+	if got := FormatRuleDiff(changes); got != want {
+		t.Errorf("FormatRuleDiff() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,315 @@
+package RedisCommon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OrderedRule is one rule of an edited rule set, listed in order of precedence. BaseIndex is the index of the rule
+// in the rule set the edits were made against, or -1 for a new rule, and Rule is the rule as it will be committed.
+type OrderedRule struct {
+	BaseIndex int
+	Rule      Rule
+}
+
+// BaseOrder lists rules unchanged, the starting point for editing them.
+func BaseOrder(rules []Rule) []OrderedRule {
+	order := make([]OrderedRule, len(rules))
+	for i, r := range rules {
+		order[i] = OrderedRule{BaseIndex: i, Rule: r}
+	}
+	return order
+}
+
+// RulePosition is the precedence new rules are inserted at among the current rules, 0 being the highest. New rules
+// are inserted ahead of the rule that had that precedence.
+type RulePosition int
+
+const (
+	// TopPosition gives new rules precedence over all current rules.
+	TopPosition RulePosition = 0
+	// BottomPosition appends new rules after all current rules.
+	BottomPosition RulePosition = -1
+)
+
+// ParseRulePosition parses top, bottom or a precedence.
+func ParseRulePosition(position string) (RulePosition, error) {
+	switch strings.ToLower(strings.TrimSpace(position)) {
+	case "top":
+		return TopPosition, nil
+	case "bottom":
+		return BottomPosition, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(position))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w '%s': expected top, bottom or a precedence starting at 0", ErrInvalidRulePosition, position)
+	}
+	return RulePosition(n), nil
+}
+
+func (p RulePosition) String() string {
+	switch p {
+	case TopPosition:
+		return "top"
+	case BottomPosition:
+		return "bottom"
+	}
+	return strconv.Itoa(int(p))
+}
+
+// Index returns the index new rules are inserted at among count current rules.
+func (p RulePosition) Index(count int) (int, error) {
+	if p == BottomPosition {
+		return count, nil
+	}
+	if int(p) > count {
+		return 0, fmt.Errorf("%w %d: there are only %d rules, the lowest position is %d", ErrInvalidRulePosition, p, count, count)
+	}
+	return int(p), nil
+}
+
+// insertRules places new rules among the current ones at position.
+func insertRules(rules []Rule, currentRules []Rule, position RulePosition) ([]Rule, error) {
+	i, err := position.Index(len(currentRules))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Rule, 0, len(rules)+len(currentRules))
+	res = append(res, currentRules[:i]...)
+	res = append(res, rules...)
+	return append(res, currentRules[i:]...), nil
+}
+
+// applyRuleOrder returns the rules of order, checking that it refers to each of baseRules at most once.
+func applyRuleOrder(baseRules []Rule, order []OrderedRule) ([]Rule, error) {
+	seen := make(map[int]bool)
+	rules := make([]Rule, len(order))
+	for i, o := range order {
+		if o.BaseIndex >= len(baseRules) {
+			return nil, fmt.Errorf("%w: unable to update rule %d, only %d rules exist", ErrRulesOutOfSync, o.BaseIndex, len(baseRules))
+		}
+		if o.BaseIndex >= 0 && seen[o.BaseIndex] {
+			return nil, fmt.Errorf("%w: rule %d is listed more than once", ErrRulesOutOfSync, o.BaseIndex)
+		}
+		seen[o.BaseIndex] = true
+		rules[i] = o.Rule
+	}
+	return rules, nil
+}
+
+// MovedRules returns the positions in order of the existing rules that were moved. The rules that keep their
+// relative order are the longest run of increasing base indexes, every other existing rule counts as moved.
+func MovedRules(order []OrderedRule) map[int]bool {
+	// length[i] is the length of the longest increasing run ending at i, prev[i] the position before i in it.
+	length := make([]int, len(order))
+	prev := make([]int, len(order))
+	best := -1
+	for i, o := range order {
+		prev[i] = -1
+		if o.BaseIndex < 0 {
+			continue
+		}
+		length[i] = 1
+		for j := 0; j < i; j++ {
+			if order[j].BaseIndex >= 0 && order[j].BaseIndex < o.BaseIndex && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+				prev[i] = j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+
+	inPlace := make(map[int]bool)
+	for i := best; i >= 0; i = prev[i] {
+		inPlace[i] = true
+	}
+
+	moved := make(map[int]bool)
+	for i, o := range order {
+		if o.BaseIndex >= 0 && !inPlace[i] {
+			moved[i] = true
+		}
+	}
+	return moved
+}
+
+// DeletedRules returns the rules of baseRules that order no longer lists, by index.
+func DeletedRules(baseRules []Rule, order []OrderedRule) map[int]Rule {
+	kept := make(map[int]bool)
+	for _, o := range order {
+		kept[o.BaseIndex] = true
+	}
+
+	deleted := make(map[int]Rule)
+	for i, r := range baseRules {
+		if !kept[i] {
+			deleted[i] = r
+		}
+	}
+	return deleted
+}
+
+// HasRuleChanges reports whether order differs from baseRules.
+func HasRuleChanges(baseRules []Rule, order []OrderedRule) bool {
+	if len(order) != len(baseRules) {
+		return true
+	}
+	for i, o := range order {
+		if o.BaseIndex != i || !o.Rule.Equal(baseRules[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanRuleUpdates computes the order, in the shape UpdateRules takes, that turns current into desired. Rules that
+// appear in both are kept, and moved if needed, rules whose conditions appear in both with a different TTL are
+// updated, and the remaining rules are added or deleted.
+func PlanRuleUpdates(current []Rule, desired []Rule) []OrderedRule {
+	order := make([]OrderedRule, len(desired))
+	for j, r := range desired {
+		order[j] = OrderedRule{BaseIndex: -1, Rule: r}
+	}
+
+	used := make(map[int]bool)
+	for _, c := range DiffRules(current, desired) {
+		if c.Kind == Unchanged {
+			order[c.NewIndex].BaseIndex = c.OldIndex
+			used[c.OldIndex] = true
+		}
+	}
+
+	sameConditions := func(a Rule, b Rule) bool {
+		a.Ttl, b.Ttl = "", ""
+		return a.Equal(b)
+	}
+
+	for _, match := range []func(a Rule, b Rule) bool{Rule.Equal, sameConditions} {
+		for j := range order {
+			if order[j].BaseIndex >= 0 {
+				continue
+			}
+			for i, r := range current {
+				if !used[i] && match(r, order[j].Rule) {
+					order[j].BaseIndex = i
+					used[i] = true
+					break
+				}
+			}
+		}
+	}
+
+	return order
+}
+
+// RebaseRuleOrder maps an order made against baseRules onto currentRules. Existing rules are followed to their new
+// position. Entries whose base rule was changed or removed in the meantime are dropped, and their positions in order
+// returned. Rules added to currentRules in the meantime are inserted after the rule that precedes them there.
+// sources holds the position in order of each rebased entry, or -1 for the inserted rules.
+func RebaseRuleOrder(baseRules []Rule, currentRules []Rule, order []OrderedRule) (rebased []OrderedRule, sources []int, dropped []int) {
+	newIndexes := make(map[int]int)
+	for _, c := range DiffRules(baseRules, currentRules) {
+		if c.Kind == Unchanged {
+			newIndexes[c.OldIndex] = c.NewIndex
+		}
+	}
+
+	rebased = make([]OrderedRule, 0, len(order)+len(currentRules))
+	sources = make([]int, 0, len(order)+len(currentRules))
+	dropped = make([]int, 0)
+	listed := make(map[int]bool)
+	for i, o := range order {
+		if o.BaseIndex >= 0 {
+			newIdx, ok := newIndexes[o.BaseIndex]
+			if !ok {
+				dropped = append(dropped, i)
+				continue
+			}
+			o.BaseIndex = newIdx
+			listed[newIdx] = true
+		}
+		rebased = append(rebased, o)
+		sources = append(sources, i)
+	}
+
+	for c, r := range currentRules {
+		if listed[c] {
+			continue
+		}
+
+		// Insert after the listed rule that comes last before c in currentRules, or after the leading new rules.
+		pos := 0
+		for pos < len(rebased) && rebased[pos].BaseIndex < 0 {
+			pos++
+		}
+		for k, o := range rebased {
+			if o.BaseIndex >= 0 && o.BaseIndex < c {
+				pos = k + 1
+			}
+		}
+
+		rebased = append(rebased[:pos], append([]OrderedRule{{BaseIndex: c, Rule: r}}, rebased[pos:]...)...)
+		sources = append(sources[:pos], append([]int{-1}, sources[pos:]...)...)
+		listed[c] = true
+	}
+
+	return rebased, sources, dropped
+}
+
+// FormatRuleUpdates renders the rules to add, update, move and delete as they are listed before being committed.
+func FormatRuleUpdates(baseRules []Rule, order []OrderedRule) string {
+	body := strings.Builder{}
+	moved := MovedRules(order)
+
+	added := make([]string, 0)
+	updated := make([]string, 0)
+	movedRules := make([]string, 0)
+	for i, o := range order {
+		switch {
+		case o.BaseIndex < 0:
+			added = append(added, fmt.Sprintf("At %d:\n%s", i, o.Rule.Formatted()))
+		case !o.Rule.Equal(baseRules[o.BaseIndex]):
+			updated = append(updated, o.Rule.Formatted())
+		}
+		if moved[i] {
+			movedRules = append(movedRules, fmt.Sprintf("%d -> %d: %s\n", o.BaseIndex, i, o.Rule.Summary()))
+		}
+	}
+
+	if len(added) > 0 {
+		body.WriteString("====== Rules to Add ======\n")
+		for _, r := range added {
+			body.WriteString(r + "\n")
+		}
+	}
+
+	if len(updated) > 0 {
+		body.WriteString("\n\n====== Rules To Update ======\n")
+		for _, r := range updated {
+			body.WriteString(fmt.Sprintf("%s\n", r))
+		}
+	}
+
+	if len(movedRules) > 0 {
+		body.WriteString("\n\n====== Rules To Move ======\n")
+		for _, r := range movedRules {
+			body.WriteString(r)
+		}
+		body.WriteString("\n")
+	}
+
+	deleted := DeletedRules(baseRules, order)
+	if len(deleted) > 0 {
+		body.WriteString("\n\n====== Rules to Delete ======\n")
+		for _, i := range sortedIndexes(deleted) {
+			body.WriteString(fmt.Sprintf("%s\n", deleted[i].Formatted()))
+		}
+	}
+
+	return strings.TrimLeft(body.String(), "\n")
+}
@@ -0,0 +1,247 @@
+package RedisCommon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// tableRule is a rule caching the queries of a table, to tell rules apart in tests.
+func tableRule(table string) Rule {
+	return Rule{TablesAny: []string{table}, Ttl: "5m"}
+}
+
+// orderOf builds an order from base indexes, taking the rules from baseRules and tableRule("new") for -1.
+func orderOf(baseRules []Rule, baseIndexes ...int) []OrderedRule {
+	order := make([]OrderedRule, len(baseIndexes))
+	for i, b := range baseIndexes {
+		r := tableRule("new")
+		if b >= 0 {
+			r = baseRules[b]
+		}
+		order[i] = OrderedRule{BaseIndex: b, Rule: r}
+	}
+	return order
+}
+
+func TestMovedRules(t *testing.T) {
+	base := []Rule{tableRule("a"), tableRule("b"), tableRule("c"), tableRule("d"), tableRule("e")}
+
+	tests := []struct {
+		name        string
+		baseIndexes []int
+		want        map[int]bool
+	}{
+		{"identity", []int{0, 1, 2, 3, 4}, map[int]bool{}},
+		{"move down", []int{1, 2, 0, 3, 4}, map[int]bool{2: true}},
+		{"move up", []int{0, 4, 1, 2, 3}, map[int]bool{1: true}},
+		{"move to the bottom", []int{1, 2, 3, 4, 0}, map[int]bool{4: true}},
+		{"two moves", []int{4, 1, 2, 3, 0}, map[int]bool{0: true, 4: true}},
+		{"inserts are not moves", []int{-1, 0, 1, -1, 2, 3, 4}, map[int]bool{}},
+		{"deletes are not moves", []int{0, 2, 4}, map[int]bool{}},
+		{"move mixed with an insert and a delete", []int{-1, 1, 2, 3, 0}, map[int]bool{4: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MovedRules(orderOf(base, tt.baseIndexes...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MovedRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRuleOrder(t *testing.T) {
+	base := []Rule{tableRule("a"), tableRule("b"), tableRule("c")}
+
+	t.Run("identity", func(t *testing.T) {
+		got, err := applyRuleOrder(base, BaseOrder(base))
+		if err != nil || !reflect.DeepEqual(got, base) {
+			t.Errorf("applyRuleOrder() = %v, %v, want %v", got, err, base)
+		}
+	})
+
+	t.Run("moves, inserts and deletes", func(t *testing.T) {
+		order := orderOf(base, 2, -1, 0, -1)
+		order[2].Rule.Ttl = "1h"
+		want := []Rule{tableRule("c"), tableRule("new"), {TablesAny: []string{"a"}, Ttl: "1h"}, tableRule("new")}
+
+		got, err := applyRuleOrder(base, order)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("applyRuleOrder() = %v, %v, want %v", got, err, want)
+		}
+	})
+
+	for _, tt := range []struct {
+		name        string
+		baseIndexes []int
+	}{
+		{"rule out of range", []int{0, 1, 3}},
+		{"rule listed twice", []int{0, 1, 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			order := orderOf(append(base, tableRule("d")), tt.baseIndexes...)
+			_, err := applyRuleOrder(base, order)
+			if !errors.Is(err, ErrRulesOutOfSync) {
+				t.Errorf("applyRuleOrder() error = %v, want ErrRulesOutOfSync", err)
+			}
+		})
+	}
+}
+
+func TestRebaseRuleOrder(t *testing.T) {
+	a, b, c := tableRule("a"), tableRule("b"), tableRule("c")
+	x, y := tableRule("x"), tableRule("y")
+	changedB := Rule{TablesAny: []string{"b"}, Ttl: "1h"}
+
+	tests := []struct {
+		name        string
+		base        []Rule
+		current     []Rule
+		order       []OrderedRule
+		want        []OrderedRule
+		wantSources []int
+		wantDropped []int
+	}{
+		{
+			name:        "identity",
+			base:        []Rule{a, b, c},
+			current:     []Rule{a, b, c},
+			order:       []OrderedRule{{0, a}, {1, b}, {2, c}},
+			want:        []OrderedRule{{0, a}, {1, b}, {2, c}},
+			wantSources: []int{0, 1, 2},
+			wantDropped: []int{},
+		},
+		{
+			name:        "edits against unchanged rules are kept",
+			base:        []Rule{a, b, c},
+			current:     []Rule{a, b, c},
+			order:       []OrderedRule{{2, c}, {-1, x}, {0, a}, {1, changedB}},
+			want:        []OrderedRule{{2, c}, {-1, x}, {0, a}, {1, changedB}},
+			wantSources: []int{0, 1, 2, 3},
+			wantDropped: []int{},
+		},
+		{
+			name:        "a rule inserted at the top by someone else",
+			base:        []Rule{a, b, c},
+			current:     []Rule{x, a, b, c},
+			order:       []OrderedRule{{2, c}, {0, a}, {1, b}},
+			want:        []OrderedRule{{0, x}, {3, c}, {1, a}, {2, b}},
+			wantSources: []int{-1, 0, 1, 2},
+			wantDropped: []int{},
+		},
+		{
+			name:        "a rule appended by someone else follows the rule before it",
+			base:        []Rule{a, b},
+			current:     []Rule{a, b, y},
+			order:       []OrderedRule{{-1, x}, {0, a}, {1, b}},
+			want:        []OrderedRule{{-1, x}, {0, a}, {1, b}, {2, y}},
+			wantSources: []int{0, 1, 2, -1},
+			wantDropped: []int{},
+		},
+		{
+			name:        "an edit of a rule deleted by someone else is dropped",
+			base:        []Rule{a, b, c},
+			current:     []Rule{a, c},
+			order:       []OrderedRule{{0, a}, {1, changedB}, {2, c}},
+			want:        []OrderedRule{{0, a}, {1, c}},
+			wantSources: []int{0, 2},
+			wantDropped: []int{1},
+		},
+		{
+			name:        "a move of a rule changed by someone else is dropped",
+			base:        []Rule{a, b, c},
+			current:     []Rule{a, changedB, c},
+			order:       []OrderedRule{{1, b}, {0, a}, {2, c}},
+			want:        []OrderedRule{{0, a}, {1, changedB}, {2, c}},
+			wantSources: []int{1, -1, 2},
+			wantDropped: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sources, dropped := RebaseRuleOrder(tt.base, tt.current, tt.order)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RebaseRuleOrder() rebased = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("RebaseRuleOrder() sources = %v, want %v", sources, tt.wantSources)
+			}
+			if !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("RebaseRuleOrder() dropped = %v, want %v", dropped, tt.wantDropped)
+			}
+
+			if _, err := applyRuleOrder(tt.current, got); err != nil {
+				t.Errorf("applyRuleOrder() of the rebased order error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPlanRuleUpdates(t *testing.T) {
+	a, b, c, d := tableRule("a"), tableRule("b"), tableRule("c"), tableRule("d")
+	longerB := Rule{TablesAny: []string{"b"}, Ttl: "1h"}
+
+	tests := []struct {
+		name        string
+		current     []Rule
+		desired     []Rule
+		want        []OrderedRule
+		wantChanges bool
+	}{
+		{
+			name:        "import into an empty rule set",
+			current:     []Rule{},
+			desired:     []Rule{a, b},
+			want:        []OrderedRule{{-1, a}, {-1, b}},
+			wantChanges: true,
+		},
+		{
+			name:        "identical rules",
+			current:     []Rule{a, b, c},
+			desired:     []Rule{a, b, c},
+			want:        []OrderedRule{{0, a}, {1, b}, {2, c}},
+			wantChanges: false,
+		},
+		{
+			name:        "partial overlap",
+			current:     []Rule{a, b, c},
+			desired:     []Rule{c, longerB, d},
+			want:        []OrderedRule{{2, c}, {1, longerB}, {-1, d}},
+			wantChanges: true,
+		},
+		{
+			name:        "a duplicate of a kept rule is added",
+			current:     []Rule{a},
+			desired:     []Rule{a, a},
+			want:        []OrderedRule{{0, a}, {-1, a}},
+			wantChanges: true,
+		},
+		{
+			name:        "an empty file deletes every rule",
+			current:     []Rule{a, b},
+			desired:     []Rule{},
+			want:        []OrderedRule{},
+			wantChanges: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanRuleUpdates(tt.current, tt.desired)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanRuleUpdates() = %v, want %v", got, tt.want)
+			}
+			if changes := HasRuleChanges(tt.current, got); changes != tt.wantChanges {
+				t.Errorf("HasRuleChanges() = %v, want %v", changes, tt.wantChanges)
+			}
+
+			applied, err := applyRuleOrder(tt.current, got)
+			if err != nil || !reflect.DeepEqual(applied, tt.desired) {
+				t.Errorf("applyRuleOrder() = %v, %v, want %v", applied, err, tt.desired)
+			}
+		})
+	}
+}
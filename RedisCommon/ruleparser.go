@@ -0,0 +1,224 @@
+package RedisCommon
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rule components as they appear in the Jackson-style property keys of the config stream, e.g. rules.1.ttl or
+// rules.2.tables-any.1.
+const (
+	ttlComponent       = "ttl"
+	regexComponent     = "regex"
+	tablesComponent    = "tables"
+	tablesAnyComponent = "tables-any"
+	tablesAllComponent = "tables-all"
+	queryIdsComponent  = "query-ids"
+)
+
+// The marker written for an empty rule set. The Jackson properties format of the config stream cannot express an
+// empty list, which only exists through its indexed elements, and XADD needs at least one field. Smart Cache (https://github.com/redis-field-engineering/redis-smart-cache) binds the rules.* properties of
+// the latest entry into its rule list and leaves other properties alone, so an entry holding only the marker leaves
+// it with no rules. Earlier versions of this CLI relied on the same behaviour with their rule.1.ttl placeholder,
+// which is also outside of rules.*.
+const (
+	emptyRulesKey   = "rules-empty"
+	emptyRulesValue = "true"
+)
+
+// The malformed placeholder earlier versions wrote for an empty rule set. It is still read, but no longer written.
+const (
+	legacyEmptyRulesKey   = "rule.1.ttl"
+	legacyEmptyRulesValue = "0s"
+)
+
+// ErrMalformedConfig is returned when an entry of the config stream contains keys that are not valid rule properties.
+var ErrMalformedConfig = errors.New("malformed rules config")
+
+// RuleKeyError describes a single malformed or missing property key in a config stream entry.
+type RuleKeyError struct {
+	EntryId string
+	Key     string
+	Reason  string
+}
+
+func (e *RuleKeyError) Error() string {
+	return fmt.Sprintf("config entry %s: key '%s': %s", e.EntryId, e.Key, e.Reason)
+}
+
+func (e *RuleKeyError) Unwrap() error {
+	return ErrMalformedConfig
+}
+
+func isArrayComponent(component string) bool {
+	switch component {
+	case tablesComponent, tablesAnyComponent, tablesAllComponent, queryIdsComponent:
+		return true
+	}
+	return false
+}
+
+// parseIndex parses a 1-based Jackson index, rejecting forms such as 01 or +1 that would alias another key.
+func parseIndex(s string) (int, bool) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 1 || strconv.Itoa(i) != s {
+		return 0, false
+	}
+	return i, true
+}
+
+type parsedRule struct {
+	ttl    *string
+	regex  *string
+	arrays map[string]map[int]string
+}
+
+func (p *parsedRule) array(component string) []string {
+	elements, ok := p.arrays[component]
+	if !ok {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(elements))
+	for i := range elements {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	res := make([]string, len(indexes))
+	for i, idx := range indexes {
+		res[i] = elements[idx]
+	}
+	return res
+}
+
+func (p *parsedRule) toRule() Rule {
+	return Rule{
+		Regex:     p.regex,
+		Tables:    p.array(tablesComponent),
+		TablesAny: p.array(tablesAnyComponent),
+		TablesAll: p.array(tablesAllComponent),
+		QueryIds:  p.array(queryIdsComponent),
+		Ttl:       *p.ttl,
+	}
+}
+
+// isEmptyRules reports whether the fields of a config stream entry are the marker of an empty rule set, or the
+// placeholder earlier versions wrote instead.
+func isEmptyRules(values map[string]interface{}) bool {
+	if len(values) != 1 {
+		return false
+	}
+	if v, ok := values[emptyRulesKey]; ok {
+		return v == emptyRulesValue
+	}
+	v, ok := values[legacyEmptyRulesKey]
+	return ok && v == legacyEmptyRulesValue
+}
+
+// ParseRules parses the fields of a config stream entry into the ordered rule set they describe. Rules and array
+// elements are ordered by their indices, gaps in the numbering are closed up, and every key that is not a valid
+// rule property, as well as the ttl of a rule that has none, is reported as a RuleKeyError carrying the entry ID.
+// Parsing the fields serializeRules writes gives back the rules it was given.
+func ParseRules(entryId string, values map[string]interface{}) ([]Rule, error) {
+	if isEmptyRules(values) {
+		return make([]Rule, 0), nil
+	}
+
+	parsed := make(map[int]*parsedRule)
+	var errs []error
+	fail := func(key string, reason string) {
+		errs = append(errs, &RuleKeyError{EntryId: entryId, Key: key, Reason: reason})
+	}
+
+	for key, raw := range values {
+		value, ok := raw.(string)
+		if !ok {
+			fail(key, fmt.Sprintf("value is %T, not a string", raw))
+			continue
+		}
+
+		split := strings.Split(key, ".")
+		if split[0] != "rules" {
+			fail(key, "not a rules property")
+			continue
+		}
+
+		if len(split) < 3 || len(split) > 4 {
+			fail(key, "expected rules.<n>.<property> or rules.<n>.<property>.<i>")
+			continue
+		}
+
+		ruleNum, ok := parseIndex(split[1])
+		if !ok {
+			fail(key, fmt.Sprintf("invalid rule number '%s'", split[1]))
+			continue
+		}
+
+		rule, ok := parsed[ruleNum]
+		if !ok {
+			rule = &parsedRule{arrays: make(map[string]map[int]string)}
+			parsed[ruleNum] = rule
+		}
+
+		component := split[2]
+		switch {
+		case component == ttlComponent || component == regexComponent:
+			if len(split) != 3 {
+				fail(key, fmt.Sprintf("'%s' is not an array", component))
+				continue
+			}
+			v := value
+			if component == ttlComponent {
+				rule.ttl = &v
+			} else {
+				rule.regex = &v
+			}
+		case isArrayComponent(component):
+			if len(split) != 4 {
+				fail(key, fmt.Sprintf("'%s' requires an element index", component))
+				continue
+			}
+			idx, ok := parseIndex(split[3])
+			if !ok {
+				fail(key, fmt.Sprintf("invalid element index '%s'", split[3]))
+				continue
+			}
+			if rule.arrays[component] == nil {
+				rule.arrays[component] = make(map[int]string)
+			}
+			rule.arrays[component][idx] = value
+		default:
+			fail(key, fmt.Sprintf("unknown property '%s'", component))
+		}
+	}
+
+	for n, rule := range parsed {
+		if rule.ttl == nil {
+			fail(fmt.Sprintf("rules.%d.%s", n, ttlComponent), "missing, every rule needs a ttl")
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].(*RuleKeyError).Key < errs[j].(*RuleKeyError).Key
+		})
+		return nil, errors.Join(errs...)
+	}
+
+	ruleNums := make([]int, 0, len(parsed))
+	for n := range parsed {
+		ruleNums = append(ruleNums, n)
+	}
+	sort.Ints(ruleNums)
+
+	rules := make([]Rule, len(ruleNums))
+	for i, n := range ruleNums {
+		rules[i] = parsed[n].toRule()
+	}
+
+	return rules, nil
+}
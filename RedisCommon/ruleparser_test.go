@@ -0,0 +1,167 @@
+package RedisCommon
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// streamValues turns serialized fields into the values map a stream entry is read as.
+func streamValues(fields []string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for i := 0; i < len(fields); i += 2 {
+		values[fields[i]] = fields[i+1]
+	}
+	return values
+}
+
+func TestParseRulesRoundTrip(t *testing.T) {
+	manyTables := []string{"t1", "t2", "t3", "t4", "t5", "t6", "t7", "t8", "t9", "t10", "t11"}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+	}{
+		{"no rules", []Rule{}},
+		{"ttl only", []Rule{{Ttl: "5m"}}},
+		{"no-cache rule", []Rule{{TablesAny: []string{"orders"}, Ttl: "0s"}}},
+		{"tables", []Rule{{Tables: []string{"orders", "customers"}, Ttl: "1h"}}},
+		{"tables-any", []Rule{{TablesAny: []string{"products"}, Ttl: "1h"}}},
+		{"tables-all", []Rule{{TablesAll: []string{"orders", "order_items"}, Ttl: "30s"}}},
+		{"query ids", []Rule{{QueryIds: []string{"8c61a5b2", "1f0e9d37"}, Ttl: "2d"}}},
+		{"regex with dots", []Rule{{Regex: strPtr(`SELECT .* FROM orders\..*`), Ttl: "5m"}}},
+		{"empty regex", []Rule{{Regex: strPtr(""), Ttl: "5m"}}},
+		{"more than nine elements keep their order", []Rule{{TablesAny: manyTables, Ttl: "5m"}}},
+		{"composite", []Rule{{
+			Tables:    []string{"orders"},
+			TablesAny: []string{"customers"},
+			TablesAll: []string{"orders", "customers"},
+			Regex:     strPtr("SELECT"),
+			QueryIds:  []string{"q1"},
+			Ttl:       "10m",
+		}}},
+		{"more than nine rules keep their order", func() []Rule {
+			rules := make([]Rule, 12)
+			for i := range rules {
+				rules[i] = Rule{TablesAny: []string{manyTables[i%len(manyTables)]}, Ttl: strings.Repeat("1", i+1) + "s"}
+			}
+			return rules
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := serializeRules(tt.rules)
+			if err != nil {
+				t.Fatalf("serializeRules() error = %v", err)
+			}
+
+			got, err := ParseRules("1-0", streamValues(fields))
+			if err != nil {
+				t.Fatalf("ParseRules() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.rules) {
+				t.Errorf("ParseRules() = %+v, want %+v", got, tt.rules)
+			}
+		})
+	}
+}
+
+func TestParseRulesLegacyEmptyPlaceholder(t *testing.T) {
+	got, err := ParseRules("1-0", map[string]interface{}{legacyEmptyRulesKey: legacyEmptyRulesValue})
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ParseRules() = %+v, want no rules", got)
+	}
+}
+
+func TestParseRulesClosesGaps(t *testing.T) {
+	got, err := ParseRules("1-0", map[string]interface{}{
+		"rules.3.ttl":          "1h",
+		"rules.3.query-ids.10": "q10",
+		"rules.3.query-ids.2":  "q2",
+		"rules.1.ttl":          "5m",
+		"rules.1.tables-any.5": "c",
+		"rules.1.tables-any.1": "a",
+		"rules.1.tables-any.2": "b",
+		"rules.3.query-ids.1":  "q1",
+	})
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+
+	want := []Rule{
+		{TablesAny: []string{"a", "b", "c"}, Ttl: "5m"},
+		{QueryIds: []string{"q1", "q2", "q10"}, Ttl: "1h"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRules() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		wantKey string
+	}{
+		{"missing ttl", map[string]interface{}{"rules.1.tables-any.1": "orders"}, "rules.1.ttl"},
+		{"missing ttl of a later rule", map[string]interface{}{"rules.1.ttl": "5m", "rules.2.regex": "SELECT"}, "rules.2.ttl"},
+		{"not a rules property", map[string]interface{}{"rules.1.ttl": "5m", "config.1.ttl": "5m"}, "config.1.ttl"},
+		{"placeholder next to rules", map[string]interface{}{"rules.1.ttl": "5m", legacyEmptyRulesKey: legacyEmptyRulesValue}, legacyEmptyRulesKey},
+		{"legacy placeholder with another value", map[string]interface{}{legacyEmptyRulesKey: "5m"}, legacyEmptyRulesKey},
+		{"too short", map[string]interface{}{"rules.1": "5m"}, "rules.1"},
+		{"too long", map[string]interface{}{"rules.1.ttl": "5m", "rules.1.tables.1.2": "orders"}, "rules.1.tables.1.2"},
+		{"rule number is not a number", map[string]interface{}{"rules.x.ttl": "5m"}, "rules.x.ttl"},
+		{"rule number is zero", map[string]interface{}{"rules.0.ttl": "5m"}, "rules.0.ttl"},
+		{"rule number has a leading zero", map[string]interface{}{"rules.1.ttl": "5m", "rules.01.ttl": "5m"}, "rules.01.ttl"},
+		{"ttl with an index", map[string]interface{}{"rules.1.ttl": "5m", "rules.1.ttl.1": "5m"}, "rules.1.ttl.1"},
+		{"regex with an index", map[string]interface{}{"rules.1.ttl": "5m", "rules.1.regex.1": "SELECT"}, "rules.1.regex.1"},
+		{"array without an index", map[string]interface{}{"rules.1.ttl": "5m", "rules.1.tables-any": "orders"}, "rules.1.tables-any"},
+		{"invalid element index", map[string]interface{}{"rules.1.ttl": "5m", "rules.1.tables-any.+1": "orders"}, "rules.1.tables-any.+1"},
+		{"unknown property", map[string]interface{}{"rules.1.ttl": "5m", "rules.1.tables_any.1": "orders"}, "rules.1.tables_any.1"},
+		{"value is not a string", map[string]interface{}{"rules.1.ttl": 5}, "rules.1.ttl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRules("1-0", tt.values)
+			if !errors.Is(err, ErrMalformedConfig) {
+				t.Fatalf("ParseRules() error = %v, want ErrMalformedConfig", err)
+			}
+
+			var keyErr *RuleKeyError
+			if !errors.As(err, &keyErr) || keyErr.Key != tt.wantKey || keyErr.EntryId != "1-0" {
+				t.Errorf("ParseRules() error = %v, want an error for key %s of entry 1-0", err, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestSerializeRulesRejectsEmptyLists(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"tables", Rule{Tables: []string{}, Ttl: "5m"}},
+		{"tables-any", Rule{TablesAny: []string{}, Ttl: "5m"}},
+		{"tables-all", Rule{TablesAll: []string{}, Ttl: "5m"}},
+		{"query ids", Rule{QueryIds: []string{}, Ttl: "5m"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := serializeRules([]Rule{{Ttl: "1h", TablesAny: []string{"orders"}}, tt.rule})
+			if err == nil || !strings.HasPrefix(err.Error(), "rule 1:") {
+				t.Errorf("serializeRules() error = %v, want an error for rule 1", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package RedisCommon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile is the layout of a declarative rules file, rules are listed in order of precedence:
+//
+//	application: smartcache
+//	rules:
+//	  - tablesAny: [orders]
+//	    ttl: 5m
+//	  - regex: SELECT .* FROM customers.*
+//	    ttl: 1h
+type RulesFile struct {
+	Application string `json:"application,omitempty" yaml:"application,omitempty"`
+	Rules       []Rule `json:"rules" yaml:"rules"`
+}
+
+const (
+	YamlFormat = "yaml"
+	JsonFormat = "json"
+)
+
+// FormatFromPath infers the rules file format from a file extension, defaulting to YAML.
+func FormatFromPath(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return JsonFormat
+	}
+	return YamlFormat
+}
+
+// MarshalRulesFile serializes a rules file as YAML or JSON.
+func MarshalRulesFile(file RulesFile, format string) ([]byte, error) {
+	if file.Rules == nil {
+		file.Rules = make([]Rule, 0)
+	}
+
+	switch strings.ToLower(format) {
+	case YamlFormat, "yml":
+		var b bytes.Buffer
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		err := encoder.Encode(file)
+		if err != nil {
+			return nil, err
+		}
+		return b.Bytes(), encoder.Close()
+	case JsonFormat:
+		b, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+
+	return nil, fmt.Errorf("unknown format '%s', valid formats are 'yaml' and 'json'", format)
+}
+
+// UnmarshalRulesFile parses a rules file written in YAML or JSON, rejecting unknown fields so that a misspelled
+// condition does not silently widen a rule, and checks that every rule has a TTL.
+func UnmarshalRulesFile(data []byte, format string) (RulesFile, error) {
+	var file RulesFile
+
+	switch strings.ToLower(format) {
+	case YamlFormat, "yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err := decoder.Decode(&file)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return RulesFile{}, fmt.Errorf("unable to parse rules file: %w", err)
+		}
+	case JsonFormat:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&file)
+		if err != nil {
+			return RulesFile{}, fmt.Errorf("unable to parse rules file: %w", err)
+		}
+	default:
+		return RulesFile{}, fmt.Errorf("unknown format '%s', valid formats are 'yaml' and 'json'", format)
+	}
+
+	if file.Rules == nil {
+		file.Rules = make([]Rule, 0)
+	}
+
+	for i, r := range file.Rules {
+		if r.Ttl == "" {
+			return RulesFile{}, fmt.Errorf("rule %d has no ttl", i)
+		}
+	}
+
+	return file, nil
+}
+
+func sortedIndexes(rules map[int]Rule) []int {
+	indexes := make([]int, 0, len(rules))
+	for i := range rules {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,81 @@
+package RedisCommon
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+// SmartCacheStore is where the CLI reads Smart Cache's profiled queries and tables from and reads and writes
+// its caching rules. The TUI models and commands depend on this interface rather than on a Redis client so that
+// they can run against fixtures.
+type SmartCacheStore interface {
+	ApplicationName() string
+	GetQueries() ([]*Query, error)
+	GetTables() ([]Table, error)
+	GetRules() ([]Rule, error)
+	GetRuleSet() (RuleSet, error)
+	CommitNewRules(rules []Rule, position RulePosition) (string, error)
+	UpdateRules(baseId string, order []OrderedRule) error
+	ReplaceRules(baseId string, rules []Rule) (string, error)
+	GetRuleHistory(count int64) ([]ConfigEntry, error)
+	GetRuleSetAt(entryId string) (RuleSet, error)
+	RollbackRules(baseId string, entryId string) (string, error)
+	CheckSmartCacheIndex() error
+}
+
+// RedisStore is the SmartCacheStore backed by a Redis Stack server that Smart Cache writes to.
+type RedisStore struct {
+	rdb             redis.UniversalClient
+	applicationName string
+}
+
+func NewRedisStore(rdb redis.UniversalClient, applicationName string) *RedisStore {
+	return &RedisStore{rdb: rdb, applicationName: applicationName}
+}
+
+func (s *RedisStore) ApplicationName() string {
+	return s.applicationName
+}
+
+func (s *RedisStore) GetQueries() ([]*Query, error) {
+	return GetQueries(s.rdb, s.applicationName)
+}
+
+func (s *RedisStore) GetTables() ([]Table, error) {
+	return GetTables(s.rdb, s.applicationName)
+}
+
+func (s *RedisStore) GetRules() ([]Rule, error) {
+	return GetRules(s.rdb, s.applicationName)
+}
+
+func (s *RedisStore) GetRuleSet() (RuleSet, error) {
+	return GetRuleSet(s.rdb, s.applicationName)
+}
+
+func (s *RedisStore) CommitNewRules(rules []Rule, position RulePosition) (string, error) {
+	return CommitNewRules(s.rdb, rules, position, s.applicationName)
+}
+
+func (s *RedisStore) UpdateRules(baseId string, order []OrderedRule) error {
+	return UpdateRules(s.rdb, baseId, order, s.applicationName)
+}
+
+func (s *RedisStore) ReplaceRules(baseId string, rules []Rule) (string, error) {
+	return CommitRuleSet(s.rdb, baseId, rules, s.applicationName)
+}
+
+func (s *RedisStore) GetRuleHistory(count int64) ([]ConfigEntry, error) {
+	return GetRuleHistory(s.rdb, s.applicationName, count)
+}
+
+func (s *RedisStore) GetRuleSetAt(entryId string) (RuleSet, error) {
+	return GetRuleSetAt(s.rdb, s.applicationName, entryId)
+}
+
+func (s *RedisStore) RollbackRules(baseId string, entryId string) (string, error) {
+	return RollbackRules(s.rdb, baseId, entryId, s.applicationName)
+}
+
+func (s *RedisStore) CheckSmartCacheIndex() error {
+	return CheckSmartCacheIndex(s.rdb, s.applicationName)
+}
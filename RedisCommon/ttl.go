@@ -0,0 +1,164 @@
+package RedisCommon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTtl is returned for TTLs that Smart Cache cannot parse as a duration.
+var ErrInvalidTtl = errors.New("invalid ttl")
+
+// ErrTtlOutOfBounds is returned for TTLs outside of the TTL policy.
+var ErrTtlOutOfBounds = errors.New("ttl out of bounds")
+
+// Ttl is how long Smart Cache caches the results of a query. Zero disables caching.
+type Ttl time.Duration
+
+// NoCache is the TTL of rules that disable caching for the queries they match.
+const NoCache Ttl = 0
+
+// ttlPattern is the duration format Smart Cache reads rule TTLs in: a whole number followed by a unit.
+var ttlPattern = regexp.MustCompile(`^(\d+)(ms|s|m|h|d)$`)
+
+// ttlUnits are the units Smart Cache accepts, largest first, which is the order String picks them in.
+var ttlUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+}
+
+// ParseTtl parses a TTL in the format Smart Cache accepts, e.g. 300s, 5m or 2d.
+func ParseTtl(ttl string) (Ttl, error) {
+	match := ttlPattern.FindStringSubmatch(strings.TrimSpace(ttl))
+	if match == nil {
+		return 0, fmt.Errorf("%w '%s': expected a whole number followed by one of ms, s, m, h or d", ErrInvalidTtl, ttl)
+	}
+
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	for _, u := range ttlUnits {
+		if u.suffix != match[2] {
+			continue
+		}
+		if err != nil || n > math.MaxInt64/int64(u.unit) {
+			return 0, fmt.Errorf("%w '%s': too long", ErrInvalidTtl, ttl)
+		}
+		return Ttl(time.Duration(n) * u.unit), nil
+	}
+
+	return 0, fmt.Errorf("%w '%s': unknown unit", ErrInvalidTtl, ttl)
+}
+
+// String renders the TTL in the largest unit that represents it exactly, e.g. 5m for 300s.
+func (t Ttl) String() string {
+	d := time.Duration(t)
+	if d == 0 {
+		return "0s"
+	}
+
+	for _, u := range ttlUnits {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d%s", d/u.unit, u.suffix)
+		}
+	}
+
+	// Smart Cache has no unit below a millisecond, round up so that a short TTL does not disable caching.
+	return fmt.Sprintf("%dms", (d+time.Millisecond-1)/time.Millisecond)
+}
+
+// Duration returns the TTL as a time.Duration.
+func (t Ttl) Duration() time.Duration {
+	return time.Duration(t)
+}
+
+// NormalizeTtl renders a TTL the way String does, or returns it as is if it cannot be parsed.
+func NormalizeTtl(ttl string) string {
+	if ttl == "" {
+		return ""
+	}
+
+	t, err := ParseTtl(ttl)
+	if err != nil {
+		return ttl
+	}
+	return t.String()
+}
+
+// noCacheLabel is how a TTL that disables caching is displayed.
+const noCacheLabel = "no cache"
+
+// DisplayTtl renders a TTL for display: normalized, or as "no cache" for a TTL that disables caching.
+func DisplayTtl(ttl string) string {
+	if t, err := ParseTtl(ttl); err == nil && t == NoCache {
+		return noCacheLabel
+	}
+	return NormalizeTtl(ttl)
+}
+
+// TtlSortValue is the value TTL columns sort by: the TTL in milliseconds, or -1 when there is no valid TTL so that
+// uncached queries sort first.
+func TtlSortValue(ttl string) int64 {
+	t, err := ParseTtl(ttl)
+	if err != nil {
+		return -1
+	}
+	return t.Duration().Milliseconds()
+}
+
+// TtlPolicy bounds the TTLs that can be set. A zero bound is not enforced.
+type TtlPolicy struct {
+	Min Ttl
+	Max Ttl
+}
+
+// ttlPolicy is the policy ValidateTtl enforces, set once from the command line or the connection profile.
+var ttlPolicy TtlPolicy
+
+// SetTtlPolicy sets the policy ValidateTtl enforces.
+func SetTtlPolicy(policy TtlPolicy) {
+	ttlPolicy = policy
+}
+
+// Check returns an error if the TTL is outside of the policy. The policy does not apply to NoCache.
+func (p TtlPolicy) Check(t Ttl) error {
+	if t == NoCache {
+		return nil
+	}
+	if p.Min > 0 && t < p.Min {
+		return fmt.Errorf("%w: %s is shorter than the minimum ttl of %s", ErrTtlOutOfBounds, t, p.Min)
+	}
+	if p.Max > 0 && t > p.Max {
+		return fmt.Errorf("%w: %s is longer than the maximum ttl of %s", ErrTtlOutOfBounds, t, p.Max)
+	}
+	return nil
+}
+
+// ParseRuleTtl parses the TTL of a rule and checks that it is within the TTL policy. A zero TTL disables caching and
+// is only accepted with allowNoCache, so that it cannot be set by accident.
+func ParseRuleTtl(ttl string, allowNoCache bool) (Ttl, error) {
+	t, err := ParseTtl(ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	if t == NoCache && !allowNoCache {
+		return 0, fmt.Errorf("%w '%s': a ttl of zero disables caching", ErrInvalidTtl, ttl)
+	}
+
+	return t, ttlPolicy.Check(t)
+}
+
+// ValidateTtl checks a rule TTL the way ParseRuleTtl does.
+func ValidateTtl(ttl string, allowNoCache bool) error {
+	_, err := ParseRuleTtl(ttl, allowNoCache)
+	return err
+}
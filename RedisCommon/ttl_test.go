@@ -0,0 +1,156 @@
+package RedisCommon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTtl(t *testing.T) {
+	tests := []struct {
+		ttl        string
+		want       Ttl
+		wantString string
+	}{
+		{"250ms", Ttl(250 * time.Millisecond), "250ms"},
+		{"1500ms", Ttl(1500 * time.Millisecond), "1500ms"},
+		{"2000ms", Ttl(2 * time.Second), "2s"},
+		{"45s", Ttl(45 * time.Second), "45s"},
+		{"300s", Ttl(5 * time.Minute), "5m"},
+		{"5m", Ttl(5 * time.Minute), "5m"},
+		{"90m", Ttl(90 * time.Minute), "90m"},
+		{"120m", Ttl(2 * time.Hour), "2h"},
+		{"2h", Ttl(2 * time.Hour), "2h"},
+		{"48h", Ttl(48 * time.Hour), "2d"},
+		{"7d", Ttl(7 * 24 * time.Hour), "7d"},
+		{"0s", NoCache, "0s"},
+		{"0d", NoCache, "0s"},
+		{" 5m ", Ttl(5 * time.Minute), "5m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ttl, func(t *testing.T) {
+			got, err := ParseTtl(tt.ttl)
+			if err != nil {
+				t.Fatalf("ParseTtl() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTtl() = %v, want %v", time.Duration(got), time.Duration(tt.want))
+			}
+			if got.String() != tt.wantString {
+				t.Errorf("String() = %s, want %s", got.String(), tt.wantString)
+			}
+
+			roundTrip, err := ParseTtl(got.String())
+			if err != nil || roundTrip != got {
+				t.Errorf("ParseTtl(String()) = %v, %v, want %v", time.Duration(roundTrip), err, time.Duration(got))
+			}
+		})
+	}
+}
+
+func TestParseTtlRejects(t *testing.T) {
+	for _, ttl := range []string{
+		"",
+		"5banana",
+		"5",
+		"m",
+		"-5m",
+		"+5m",
+		"1.5h",
+		"5 m",
+		"5M",
+		"1h30m",
+		"9223372036854775807ms",
+		"106752d",
+		"99999999999999999999s",
+	} {
+		t.Run(ttl, func(t *testing.T) {
+			if _, err := ParseTtl(ttl); !errors.Is(err, ErrInvalidTtl) {
+				t.Errorf("ParseTtl() error = %v, want ErrInvalidTtl", err)
+			}
+		})
+	}
+}
+
+func TestTtlStringBelowMillisecond(t *testing.T) {
+	if got := Ttl(time.Microsecond).String(); got != "1ms" {
+		t.Errorf("String() = %s, want 1ms", got)
+	}
+	if got := Ttl(1500 * time.Microsecond).String(); got != "2ms" {
+		t.Errorf("String() = %s, want 2ms", got)
+	}
+}
+
+func TestTtlPolicyCheck(t *testing.T) {
+	policy := TtlPolicy{Min: Ttl(time.Minute), Max: Ttl(24 * time.Hour)}
+
+	tests := []struct {
+		name    string
+		policy  TtlPolicy
+		ttl     Ttl
+		wantErr bool
+	}{
+		{"below the minimum", policy, Ttl(59 * time.Second), true},
+		{"at the minimum", policy, Ttl(time.Minute), false},
+		{"between the bounds", policy, Ttl(time.Hour), false},
+		{"at the maximum", policy, Ttl(24 * time.Hour), false},
+		{"above the maximum", policy, Ttl(24*time.Hour + time.Millisecond), true},
+		{"no cache is exempt", policy, NoCache, false},
+		{"no minimum", TtlPolicy{Max: Ttl(time.Hour)}, Ttl(time.Millisecond), false},
+		{"no maximum", TtlPolicy{Min: Ttl(time.Minute)}, Ttl(365 * 24 * time.Hour), false},
+		{"no policy", TtlPolicy{}, Ttl(time.Millisecond), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.ttl)
+			if tt.wantErr && !errors.Is(err, ErrTtlOutOfBounds) {
+				t.Errorf("Check() error = %v, want ErrTtlOutOfBounds", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Check() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestParseRuleTtl(t *testing.T) {
+	SetTtlPolicy(TtlPolicy{Min: Ttl(time.Minute), Max: Ttl(time.Hour)})
+	t.Cleanup(func() { SetTtlPolicy(TtlPolicy{}) })
+
+	tests := []struct {
+		name         string
+		ttl          string
+		allowNoCache bool
+		want         Ttl
+		wantErr      error
+	}{
+		{"zero without allowNoCache", "0s", false, 0, ErrInvalidTtl},
+		{"zero with allowNoCache", "0s", true, NoCache, nil},
+		{"zero in another unit with allowNoCache", "0m", true, NoCache, nil},
+		{"at the minimum", "60s", false, Ttl(time.Minute), nil},
+		{"at the maximum", "1h", false, Ttl(time.Hour), nil},
+		{"below the minimum", "59s", false, 0, ErrTtlOutOfBounds},
+		{"above the maximum", "61m", true, 0, ErrTtlOutOfBounds},
+		{"invalid", "5banana", true, 0, ErrInvalidTtl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRuleTtl(tt.ttl, tt.allowNoCache)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ParseRuleTtl() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseRuleTtl() = %v, %v, want %v", time.Duration(got), err, time.Duration(tt.want))
+			}
+			if err := ValidateTtl(tt.ttl, tt.allowNoCache); err != nil {
+				t.Errorf("ValidateTtl() error = %v", err)
+			}
+		})
+	}
+}
@@ -1,112 +1,123 @@
 package RuleDialog
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"smart-cache-cli/ConfirmationDialog"
 	"smart-cache-cli/RedisCommon"
-	"smart-cache-cli/util"
+	"smart-cache-cli/RuleMatcher"
 	"strings"
 
-	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/redis/go-redis/v9"
 )
 
-type item struct{ ruleType RedisCommon.RuleType }
-
-func (i item) FilterValue() string { return "" }
-
-type itemDelegate struct{}
-
-func (d itemDelegate) Height() int                               { return 1 }
-func (d itemDelegate) Spacing() int                              { return 0 }
-func (d itemDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
-func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
-	i, ok := listItem.(item)
-	if !ok {
-		return
-	}
-
-	str := fmt.Sprintf("%s", i.ruleType)
+var (
+	focusedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	blurredStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	noStyle      = lipgloss.NewStyle()
+	helpStyle    = blurredStyle.Copy()
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	previewStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240")).Padding(0, 1)
 
-	fn := itemStyle.Render
-	if index == m.Index() {
-		fn = func(s ...string) string {
-			return selectedItemStyle.Render("> " + s[0])
-		}
-	}
+	focusedButton = focusedStyle.Copy().Render("[ Submit ]")
+	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Submit"))
+)
 
-	fmt.Fprint(w, fn(str))
+// conditionTypes are the conditions the form has a field for, in the order Smart Cache evaluates them.
+var conditionTypes = []RedisCommon.RuleType{
+	RedisCommon.Tables,
+	RedisCommon.TablesAny,
+	RedisCommon.TablesAll,
+	RedisCommon.Regex,
+	RedisCommon.QueryIds,
 }
 
+// The fields of the form after the condition fields, in focus order.
 var (
-	focusedStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-	blurredStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	cursorStyle         = focusedStyle.Copy()
-	noStyle             = lipgloss.NewStyle()
-	itemStyle           = lipgloss.NewStyle().PaddingLeft(4)
-	selectedItemStyle   = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170"))
-	helpStyle           = blurredStyle.Copy()
-	cursorModeHelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
-
-	focusedButton = focusedStyle.Copy().Render("[ Submit ]")
-	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Submit"))
+	ttlField     = len(conditionTypes)
+	noCacheField = ttlField + 1
+	submitButton = noCacheField + 1
+	numFields    = submitButton + 1
 )
 
+const inputWidth = 80
+
 type RuleMsg struct {
 	Rule  RedisCommon.Rule
 	IsNew bool
 }
 
 type Model struct {
-	typeSelectorList list.Model
-	focusIndex       int
-	error            string
-	parentModel      tea.Model
-	rdb              *redis.Client
-	confirm          bool
-	isNew            bool
-	applicationName  string
-	ruleType         RedisCommon.RuleType
-	ttl              string
-	match            string
-	textInput        textinput.Model
-	wasPreset        bool
+	focusIndex  int
+	error       string
+	parentModel tea.Model
+	store       RedisCommon.SmartCacheStore
+	confirm     bool
+	isNew       bool
+	// original is the rule being edited, its conditions are kept as they are unless their field is changed.
+	original        RedisCommon.Rule
+	conditionInputs []textarea.Model
+	ttlInput        textinput.Model
+	noCache         bool
+	// matchAllWarned is set once a rule without conditions was submitted, submitting it again confirms it.
+	matchAllWarned bool
 }
 
-func New(parentModel tea.Model, rdb *redis.Client, rule *RedisCommon.Rule, confirm bool, applicationName string, ruleType RedisCommon.RuleType) Model {
-	items := []list.Item{
-		item{RedisCommon.Tables},
-		item{RedisCommon.TablesAll},
-		item{RedisCommon.TablesAny},
-		item{RedisCommon.Regex},
-		item{RedisCommon.All},
+func placeholder(ruleType RedisCommon.RuleType) string {
+	switch ruleType {
+	case RedisCommon.Regex:
+		return "SELECT .* FROM customers.*"
+	case RedisCommon.QueryIds:
+		return "8c61a5b2,1f0e9d37"
+	default:
+		return "orders,customers"
 	}
-	typeSelectList := list.New(items, itemDelegate{}, 50, 15)
-	typeSelectList.Title = "Select a Rule Type"
+}
 
-	ti := textinput.New()
+// New opens the form for a new rule, or pre-populated from rule to edit it.
+func New(parentModel tea.Model, store RedisCommon.SmartCacheStore, rule *RedisCommon.Rule, confirm bool) Model {
+	var original RedisCommon.Rule
+	if rule != nil {
+		original = *rule
+	}
+
+	inputs := make([]textarea.Model, len(conditionTypes))
+	for i, t := range conditionTypes {
+		ta := textarea.New()
+		ta.CharLimit = 0
+		ta.ShowLineNumbers = false
+		ta.SetWidth(inputWidth)
+		ta.SetHeight(1)
+		ta.Blur()
+		if rule != nil {
+			ta.SetValue(rule.ConditionValue(t))
+		}
+		inputs[i] = ta
+	}
 
-	ti.Focus()
-	ti.CharLimit = 30
+	ti := textinput.New()
+	ti.Placeholder = "30m"
+	ti.CharLimit = 0
 	ti.Width = 30
-	wasPreset := ruleType != RedisCommon.Unknown
+	if rule != nil && !rule.DisablesCaching() {
+		ti.SetValue(rule.Ttl)
+	}
 
 	m := Model{
-		parentModel:      parentModel,
-		rdb:              rdb,
-		confirm:          confirm,
-		isNew:            rule == nil,
-		applicationName:  applicationName,
-		typeSelectorList: typeSelectList,
-		ruleType:         ruleType,
-		textInput:        ti,
-		ttl:              "",
-		wasPreset:        wasPreset,
+		parentModel:     parentModel,
+		store:           store,
+		confirm:         confirm,
+		isNew:           rule == nil,
+		original:        original,
+		conditionInputs: inputs,
+		ttlInput:        ti,
+		noCache:         rule != nil && rule.DisablesCaching(),
 	}
+	m, _ = m.focus(0)
 
 	return m
 }
@@ -115,152 +126,311 @@ func (m Model) Init() tea.Cmd {
 	return textinput.Blink
 }
 
-func (m Model) GetRuleFromModel() (*RedisCommon.Rule, error) {
-
-	rule := RedisCommon.Rule{
-		Ttl: m.ttl,
-	}
+// parseList splits a comma- or newline-delimited list.
+func parseList(s string) []string {
+	return RuleMatcher.ParseTables(strings.ReplaceAll(s, "\n", ","))
+}
 
-	switch m.ruleType {
+// setCondition sets the condition of the given type of rule to the one of from.
+func setCondition(rule *RedisCommon.Rule, from RedisCommon.Rule, ruleType RedisCommon.RuleType) {
+	switch ruleType {
 	case RedisCommon.Tables:
-		rule.Tables = strings.Split(m.match, ",")
+		rule.Tables = from.Tables
 	case RedisCommon.TablesAny:
-		rule.TablesAny = strings.Split(m.match, ",")
+		rule.TablesAny = from.TablesAny
 	case RedisCommon.TablesAll:
-		rule.TablesAll = strings.Split(m.match, ",")
+		rule.TablesAll = from.TablesAll
 	case RedisCommon.QueryIds:
-		rule.QueryIds = strings.Split(m.match, ",")
+		rule.QueryIds = from.QueryIds
 	case RedisCommon.Regex:
-		rule.Regex = &m.match
+		rule.Regex = from.Regex
+	}
+}
+
+// GetRuleFromModel builds the rule the form describes, without validating it. Values are trimmed and a condition
+// left empty is not set. When editing a rule, the conditions whose field was not changed are kept as they are, so
+// that e.g. an empty list is not dropped.
+func (m Model) GetRuleFromModel() (*RedisCommon.Rule, error) {
+	rule := RedisCommon.Rule{
+		Ttl: RedisCommon.NormalizeTtl(strings.TrimSpace(m.ttlInput.Value())),
+	}
+	if m.noCache {
+		rule.Ttl = RedisCommon.NoCache.String()
+	}
+
+	for i, t := range conditionTypes {
+		if !m.isNew && m.conditionInputs[i].Value() == m.original.ConditionValue(t) {
+			setCondition(&rule, m.original, t)
+			continue
+		}
+
+		value := strings.TrimSpace(m.conditionInputs[i].Value())
+		list := parseList(value)
+		if (t != RedisCommon.Regex && len(list) == 0) || value == "" {
+			continue
+		}
+
+		switch t {
+		case RedisCommon.Tables:
+			rule.Tables = list
+		case RedisCommon.TablesAny:
+			rule.TablesAny = list
+		case RedisCommon.TablesAll:
+			rule.TablesAll = list
+		case RedisCommon.QueryIds:
+			rule.QueryIds = list
+		case RedisCommon.Regex:
+			rule.Regex = &value
+		}
 	}
 
 	return &rule, nil
 }
 
+// conditionError validates a condition field. Warnings are for values that are valid but cannot be fully checked.
+func (m Model) conditionError(i int) (err error, warning bool) {
+	value := strings.TrimSpace(m.conditionInputs[i].Value())
+	if conditionTypes[i] != RedisCommon.Regex || value == "" {
+		return nil, false
+	}
+
+	err = RuleMatcher.CheckRegex(value)
+	return err, err != nil && !errors.Is(err, RuleMatcher.ErrInvalidRegex)
+}
+
+// ttlError validates the TTL field, which is not used when the rule disables caching.
+func (m Model) ttlError() error {
+	if m.noCache {
+		return nil
+	}
+	return RedisCommon.ValidateTtl(m.ttlInput.Value(), false)
+}
+
+// firstInvalidField returns the first field that blocks submitting the form, or -1 if the form is valid.
+func (m Model) firstInvalidField() int {
+	for i := range conditionTypes {
+		if err, warning := m.conditionError(i); err != nil && !warning {
+			return i
+		}
+	}
+	if m.ttlError() != nil {
+		return ttlField
+	}
+	return -1
+}
+
+// focus moves the focus to field i. The focused condition field grows to show long and multi-line values.
+func (m Model) focus(i int) (Model, tea.Cmd) {
+	m.focusIndex = i
+	m.ttlInput.Blur()
+	for j := range m.conditionInputs {
+		m.conditionInputs[j].Blur()
+		m.conditionInputs[j].SetHeight(1)
+		m.conditionInputs[j].Placeholder = ""
+	}
+
+	switch {
+	case i < len(conditionTypes):
+		m.conditionInputs[i].SetHeight(4)
+		m.conditionInputs[i].Placeholder = placeholder(conditionTypes[i])
+		return m, m.conditionInputs[i].Focus()
+	case i == ttlField:
+		return m, m.ttlInput.Focus()
+	}
+	return m, nil
+}
+
+func (m Model) moveFocus(delta int) (Model, tea.Cmd) {
+	return m.focus((m.focusIndex + delta + numFields) % numFields)
+}
+
+func (m Model) submit() (tea.Model, tea.Cmd) {
+	if i := m.firstInvalidField(); i >= 0 {
+		m.error = "Please fix the highlighted fields before submitting."
+		return m.focus(i)
+	}
+	m.error = ""
+
+	rule, err := m.GetRuleFromModel()
+	if err != nil {
+		m.error = err.Error()
+		return m, nil
+	}
+
+	if rule.GetType() == RedisCommon.All && !m.matchAllWarned {
+		m.matchAllWarned = true
+		return m, nil
+	}
+
+	if !m.confirm {
+		m.parentModel, _ = m.parentModel.Update(RuleMsg{Rule: *rule, IsNew: m.isNew})
+		return m.parentModel, nil
+	}
+
+	ruleMap := make(map[string]RedisCommon.Rule)
+	ruleMap[rule.Ttl] = *rule
+	return ConfirmationDialog.New(m, ruleMap, m.store), nil
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case ConfirmationDialog.ConfirmationMessage:
+		if !msg.ConfirmedUpdate {
+			return m, nil
+		}
 		m.parentModel, _ = m.parentModel.Update(msg)
 		rule, _ := m.GetRuleFromModel()
-		_, err := RedisCommon.CommitNewRules(m.rdb, []RedisCommon.Rule{*rule}, m.applicationName)
+		_, err := m.store.CommitNewRules([]RedisCommon.Rule{*rule}, RedisCommon.TopPosition)
 		if err != nil {
 			confMsg := ConfirmationDialog.ConfirmationMessage{
-				Message: "Failed to update Redis",
+				Message: fmt.Sprintf("Failed to update Redis: %s", err),
 			}
 
 			m.parentModel, _ = m.parentModel.Update(confMsg)
 		}
 		return m.parentModel, nil
 	case tea.KeyMsg:
-		if m.ruleType == RedisCommon.Unknown {
-			m.typeSelectorList, _ = m.typeSelectorList.Update(msg)
-		}
 		switch msg.String() {
 		case tea.KeyCtrlC.String():
 			m.parentModel, _ = m.parentModel.Update(msg)
 			return m.parentModel, tea.Quit
 		case tea.KeyCtrlB.String(), tea.KeyEsc.String():
-			m.textInput.SetValue("")
-			if util.ValidateTimeout(m.ttl) == nil {
-				m.ttl = ""
-				return m, nil
+			m.parentModel, _ = m.parentModel.Update(ConfirmationDialog.ConfirmationMessage{ConfirmedUpdate: true})
+			return m.parentModel, nil
+		case tea.KeyCtrlS.String():
+			return m.submit()
+		case tea.KeyTab.String():
+			return m.moveFocus(1)
+		case tea.KeyShiftTab.String():
+			return m.moveFocus(-1)
+		}
+
+		switch {
+		case m.focusIndex < len(conditionTypes):
+			// Enter starts a new line in a condition, which is how long regexes and lists are entered.
+			var cmd tea.Cmd
+			m.conditionInputs[m.focusIndex], cmd = m.conditionInputs[m.focusIndex].Update(msg)
+			m.matchAllWarned = false
+			return m, cmd
+		case m.focusIndex == ttlField:
+			switch msg.String() {
+			case tea.KeyUp.String():
+				return m.moveFocus(-1)
+			case tea.KeyDown.String(), tea.KeyEnter.String():
+				return m.moveFocus(1)
 			}
-			if m.match != "" {
-				m.match = ""
-				return m, nil
-			} else if m.ruleType != RedisCommon.Unknown && !m.wasPreset {
-				m.ruleType = RedisCommon.Unknown
+			var cmd tea.Cmd
+			m.ttlInput, cmd = m.ttlInput.Update(msg)
+			return m, cmd
+		case m.focusIndex == noCacheField:
+			switch msg.String() {
+			case tea.KeyUp.String():
+				return m.moveFocus(-1)
+			case tea.KeyDown.String():
+				return m.moveFocus(1)
+			case tea.KeySpace.String(), tea.KeyEnter.String(), "x":
+				m.noCache = !m.noCache
+				m.matchAllWarned = false
 				return m, nil
 			}
-
-			m.parentModel, _ = m.parentModel.Update(ConfirmationDialog.ConfirmationMessage{ConfirmedUpdate: true})
-			return m.parentModel, nil
-		case tea.KeyTab.String(), tea.KeyShiftTab.String(), tea.KeyEnter.String(), tea.KeyUp.String(), tea.KeyDown.String():
-			s := msg.String()
-
-			if s == "enter" {
-				if m.ruleType == RedisCommon.Unknown {
-					i, _ := m.typeSelectorList.SelectedItem().(item)
-					m.ruleType = i.ruleType
-					return m, nil
-				} else if m.match == "" && m.ruleType != RedisCommon.All {
-					if m.textInput.Value() != "" {
-						m.match = m.textInput.Value()
-						m.textInput.SetValue("")
-						return m, nil
-					}
-				} else if util.ValidateTimeout(m.ttl) != nil {
-					candidateTtl := m.textInput.Value()
-					if util.ValidateTimeout(candidateTtl) == nil {
-						m.ttl = candidateTtl
-						m.textInput.SetValue("")
-						m.textInput.Placeholder = ""
-						rule, err := m.GetRuleFromModel()
-						if err != nil {
-							m.error = err.Error()
-							return m, nil
-						}
-
-						if !m.confirm {
-							respMsg := RuleMsg{
-								Rule:  *rule,
-								IsNew: m.isNew,
-							}
-
-							m.parentModel, _ = m.parentModel.Update(respMsg)
-							return m.parentModel, nil
-						}
-
-						ruleMap := make(map[string]RedisCommon.Rule)
-						ruleMap[rule.Ttl] = *rule
-						return ConfirmationDialog.New(m, ruleMap), nil
-					}
-				}
+		case m.focusIndex == submitButton:
+			switch msg.String() {
+			case tea.KeyUp.String():
+				return m.moveFocus(-1)
+			case tea.KeyEnter.String():
+				return m.submit()
 			}
 		}
-		if m.ruleType != RedisCommon.Unknown {
-			m.textInput, _ = m.textInput.Update(msg)
-		}
-
 	}
 	return m, nil
-
 }
 
-func (m Model) ruleSoFar() string {
-	var b strings.Builder
-	if m.ruleType != RedisCommon.Unknown {
-		b.WriteString(fmt.Sprintf("Rule Type: %s\n", m.ruleType))
+func (m Model) label(i int, s string) string {
+	if m.focusIndex == i {
+		return focusedStyle.Render("> " + s)
 	}
-	if m.match != "" {
-		b.WriteString(fmt.Sprintf("Match Against: %s\n", m.match))
+	return noStyle.Render("  " + s)
+}
+
+// fieldStatus renders the inline validation result of a field.
+func fieldStatus(err error, warning bool) string {
+	switch {
+	case err == nil:
+		return ""
+	case warning:
+		return warningStyle.Render("  Warning: "+err.Error()) + "\n"
+	default:
+		return errorStyle.Render("  "+err.Error()) + "\n"
 	}
+}
 
-	return b.String()
+func conditionLabel(ruleType RedisCommon.RuleType) string {
+	switch ruleType {
+	case RedisCommon.Tables:
+		return "Tables Exact, the query uses exactly these tables:"
+	case RedisCommon.TablesAny:
+		return "Tables Any, the query uses at least one of these tables:"
+	case RedisCommon.TablesAll:
+		return "Tables All, the query uses all of these tables and possibly others:"
+	case RedisCommon.Regex:
+		return "Regex, the SQL of the query matches this regular expression in full:"
+	default:
+		return "Query IDs, the query has one of these IDs:"
+	}
 }
 
 func (m Model) View() string {
 	var b strings.Builder
 
-	if m.ruleType == RedisCommon.Unknown {
-		b.WriteString("  == Create rule menu ==\n\n  Select the type of rule you want to create.\n  Press [CTRL+B] to return to the previous screen.\n\n")
-		b.WriteString(m.typeSelectorList.View())
-		return b.String()
-	} else if m.match == "" && m.ruleType != RedisCommon.All {
-		b.WriteString(m.ruleSoFar())
-		if m.ruleType == RedisCommon.Regex {
-			b.WriteString("Enter a regular expression to match against:")
-		} else if m.ruleType == RedisCommon.QueryIds {
-			b.WriteString("Enter a comma-separated list of Query IDs to match against:")
-		} else {
-			b.WriteString("Enter a comma-separated list of tables to match against:")
-		}
-		b.WriteString(m.textInput.View())
+	title := "== Create rule =="
+	if !m.isNew {
+		title = "== Edit rule =="
+	}
+	b.WriteString(title + "\n\n")
+
+	b.WriteString(helpStyle.Render("A rule matches the queries that meet all conditions that are filled in, leave them all empty to match every query.\nLists are separated by commas or new lines.") + "\n\n")
+	for i, t := range conditionTypes {
+		b.WriteString(m.label(i, conditionLabel(t)) + "\n")
+		b.WriteString(m.conditionInputs[i].View() + "\n")
+		b.WriteString(fieldStatus(m.conditionError(i)))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(m.label(ttlField, "TTL as a duration (e.g. 1h, 300s, 5m): "))
+	if m.noCache {
+		b.WriteString(blurredStyle.Render("not used, the rule disables caching") + "\n")
+	} else {
+		b.WriteString(m.ttlInput.View() + "\n")
+	}
+	if m.ttlInput.Value() != "" || m.focusIndex > ttlField {
+		b.WriteString(fieldStatus(m.ttlError(), false))
+	}
+
+	checkbox := "[ ]"
+	if m.noCache {
+		checkbox = "[x]"
+	}
+	b.WriteString(m.label(noCacheField, checkbox+" Disable caching for the queries this rule matches") + "\n\n")
+
+	if m.focusIndex == submitButton {
+		b.WriteString(focusedButton)
 	} else {
-		b.WriteString(m.ruleSoFar())
-		b.WriteString("Enter a TTL in the form of a duration (e.g. 1h, 300s, 5m):")
-		b.WriteString(m.textInput.View())
+		b.WriteString(blurredButton)
+	}
+	b.WriteString("\n")
+
+	if m.error != "" {
+		b.WriteString("\n" + errorStyle.Render(m.error) + "\n")
 	}
+	if m.matchAllWarned {
+		b.WriteString("\n" + warningStyle.Render("The rule has no conditions and matches every query, submit again to confirm.") + "\n")
+	}
+
+	rule, _ := m.GetRuleFromModel()
+	b.WriteString("\nPreview:\n")
+	b.WriteString(previewStyle.Render(strings.TrimRight(rule.Formatted(), "\n")) + "\n")
+
+	b.WriteString("\n" + helpStyle.Render("[TAB]/[SHIFT+TAB] to move between fields, [SPACE] to toggle disabling caching, [CTRL+S] to submit, [CTRL+B] or [ESC] to go back"))
 
 	return b.String()
 }
@@ -0,0 +1,270 @@
+package RuleHistory
+
+import (
+	"fmt"
+	"smart-cache-cli/ConfirmationDialog"
+	"smart-cache-cli/RedisCommon"
+	"smart-cache-cli/util"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/evertras/bubble-table/table"
+)
+
+var (
+	customBorder = table.Border{
+		Top:    "─",
+		Left:   "│",
+		Right:  "│",
+		Bottom: "─",
+
+		TopRight:    "╮",
+		TopLeft:     "╭",
+		BottomRight: "╯",
+		BottomLeft:  "╰",
+
+		TopJunction:    "╥",
+		LeftJunction:   "├",
+		RightJunction:  "┤",
+		BottomJunction: "╨",
+		InnerJunction:  "╫",
+
+		InnerDivider: "║",
+	}
+
+	markedStyle = lipgloss.NewStyle().Background(lipgloss.Color("11")).Foreground(lipgloss.Color("0"))
+)
+
+// historyLength is the number of config entries the screen loads.
+const historyLength = 100
+
+type Model struct {
+	parentModel tea.Model
+	table       table.Model
+	entries     []RedisCommon.ConfigEntry
+	store       RedisCommon.SmartCacheStore
+	// marked holds the row ids of the entries selected for a diff, at most two.
+	marked   []int
+	diff     string
+	rollback *RedisCommon.ConfigEntry
+	headId   string
+	message  string
+	err      error
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) highlighted() (int, bool) {
+	if len(m.entries) == 0 {
+		return -1, false
+	}
+	return m.table.HighlightedRow().Data["RowId"].(int), true
+}
+
+func (m Model) toggleMark(rowId int) Model {
+	for i, r := range m.marked {
+		if r == rowId {
+			m.marked = append(m.marked[:i:i], m.marked[i+1:]...)
+			return m
+		}
+	}
+
+	if len(m.marked) == 2 {
+		m.marked = m.marked[1:]
+	}
+	m.marked = append(m.marked, rowId)
+	return m
+}
+
+// showDiff diffs the two marked entries, older to newer, or the highlighted entry against its predecessor.
+func (m Model) showDiff(rowId int) Model {
+	older, newer := rowId+1, rowId
+	if len(m.marked) == 2 {
+		older, newer = m.marked[0], m.marked[1]
+		if older < newer {
+			older, newer = newer, older
+		}
+	}
+
+	to := m.entries[newer]
+	var from RedisCommon.ConfigEntry
+	if older < len(m.entries) {
+		from = m.entries[older]
+	}
+
+	if from.Err != nil || to.Err != nil {
+		m.err = fmt.Errorf("unable to diff unreadable entries")
+		return m
+	}
+
+	if from.Id == "" {
+		m.diff = fmt.Sprintf("--- (none)\n+++ %s\n", to.Id)
+	} else {
+		m.diff = fmt.Sprintf("--- %s\n+++ %s\n", from.Id, to.Id)
+	}
+	m.diff += RedisCommon.FormatRuleDiff(RedisCommon.DiffRules(from.Rules, to.Rules))
+	return m
+}
+
+func (m Model) startRollback(rowId int) Model {
+	entry := m.entries[rowId]
+	if entry.Err != nil {
+		m.err = entry.Err
+		return m
+	}
+
+	current, err := m.store.GetRuleSet()
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	changes := RedisCommon.DiffRules(current.Rules, entry.Rules)
+	if !RedisCommon.HasChanges(changes) {
+		m.message = fmt.Sprintf("The current rules already match entry %s.", entry.Id)
+		return m
+	}
+
+	m.rollback = &entry
+	m.headId = current.Id
+	m.diff = RedisCommon.FormatRuleDiff(changes)
+	return m
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == tea.KeyCtrlC.String() {
+			return m.parentModel, tea.Quit
+		}
+
+		if m.rollback != nil {
+			switch msg.String() {
+			case "y", "Y":
+				id, err := m.store.RollbackRules(m.headId, m.rollback.Id)
+				if err != nil {
+					m.err = err
+				} else {
+					m.message = fmt.Sprintf("Rolled back to entry %s as new entry %s.", m.rollback.Id, id)
+					m.err = nil
+				}
+				m.rollback = nil
+				m.diff = ""
+				ResetModel(&m)
+			case "n", "N", "b", tea.KeyEsc.String():
+				m.rollback = nil
+				m.diff = ""
+			}
+			return m, nil
+		}
+
+		if m.diff != "" {
+			switch msg.String() {
+			case "b", tea.KeyEsc.String(), tea.KeyEnter.String():
+				m.diff = ""
+			}
+			return m, nil
+		}
+
+		rowId, ok := m.highlighted()
+		switch msg.String() {
+		case "b", tea.KeyEsc.String():
+			m.parentModel, _ = m.parentModel.Update(ConfirmationDialog.ConfirmationMessage{ConfirmedUpdate: true})
+			return m.parentModel, nil
+		case " ":
+			if ok {
+				m = m.toggleMark(rowId)
+				m.table = m.RefreshRows()
+			}
+			return m, nil
+		case tea.KeyEnter.String(), "d":
+			if ok {
+				m.err = nil
+				m = m.showDiff(rowId)
+			}
+			return m, nil
+		case "r":
+			if ok {
+				m.err = nil
+				m.message = ""
+				m = m.startRollback(rowId)
+			}
+			return m, nil
+		}
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m Model) View() string {
+	body := strings.Builder{}
+
+	if m.rollback != nil {
+		body.WriteString(fmt.Sprintf("Rolling back to entry %s changes the current rules as follows:\n", m.rollback.Id))
+		body.WriteString(m.diff)
+		body.WriteString("Do you want to continue? (y/N)")
+		return body.String()
+	}
+
+	if m.diff != "" {
+		body.WriteString(m.diff)
+		body.WriteString("\nPress 'b' to go back to the history\n")
+		return body.String()
+	}
+
+	body.WriteString("Press [SPACE] to select up to two entries to compare\n")
+	body.WriteString("Press [ENTER] to show the changes of the selected entries, or of the highlighted entry\n")
+	body.WriteString("Press 'r' to roll back to the highlighted entry\n")
+	body.WriteString("Press 'b' to go back\n\n")
+	body.WriteString(m.table.View())
+	if m.message != "" {
+		body.WriteString("\n" + m.message + "\n")
+	}
+	body.WriteString(util.StatusLine(m.err))
+
+	return body.String()
+}
+
+func (m Model) RefreshRows() table.Model {
+	rows := make([]table.Row, len(m.entries))
+	for i, e := range m.entries {
+		rows[i] = e.AsRow(i, RedisCommon.Previous(m.entries, i))
+		for _, r := range m.marked {
+			if r == i {
+				rows[i] = rows[i].WithStyle(markedStyle)
+			}
+		}
+	}
+	return m.table.WithRows(rows)
+}
+
+func ResetModel(m *Model) {
+	entries, err := m.store.GetRuleHistory(historyLength)
+	if err != nil {
+		m.err = err
+	}
+	m.entries = entries
+	m.marked = nil
+	m.table = m.RefreshRows()
+}
+
+func New(parentModel tea.Model, store RedisCommon.SmartCacheStore) Model {
+	model := Model{
+		table: table.New(RedisCommon.GetColumnsOfHistory()).
+			HeaderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)).
+			Focused(true).
+			Border(customBorder).
+			WithPageSize(10).
+			WithTargetWidth(200),
+		parentModel: parentModel,
+		store:       store,
+	}
+	ResetModel(&model)
+
+	return model
+}
@@ -0,0 +1,146 @@
+// Package RuleLint finds caching rules that are invalid, can never apply, or are likely mistakes.
+package RuleLint
+
+import (
+	"errors"
+	"fmt"
+	"smart-cache-cli/RedisCommon"
+	"smart-cache-cli/RuleMatcher"
+	"strings"
+)
+
+type Severity string
+
+const (
+	// Error findings are rules Smart Cache rejects or cannot evaluate as intended.
+	Error Severity = "error"
+	// Warning findings are rules that are valid but never apply or are likely mistakes.
+	Warning Severity = "warning"
+)
+
+// Checks, as reported in findings.
+const (
+	InvalidTtl     = "invalid-ttl"
+	TtlOutOfPolicy = "ttl-out-of-policy"
+	InvalidRegex   = "invalid-regex"
+	EmptyTables    = "empty-tables"
+	NeverMatches   = "never-matches"
+	Shadowed       = "shadowed"
+	Duplicate      = "duplicate"
+	UnknownQueryId = "unknown-query-id"
+)
+
+// Finding is a problem with the rule at Index in the linted rules.
+type Finding struct {
+	Index    int
+	Severity Severity
+	Check    string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("rule %d: %s: %s (%s)", f.Index, f.Severity, f.Message, f.Check)
+}
+
+// HasErrors reports whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTableList(index int, name string, tables []string) []Finding {
+	if tables == nil {
+		return nil
+	}
+
+	if len(tables) == 0 {
+		return []Finding{{index, Warning, EmptyTables, fmt.Sprintf("%s is set but lists no tables", name)}}
+	}
+
+	for _, t := range tables {
+		if strings.TrimSpace(t) == "" {
+			return []Finding{{index, Warning, EmptyTables, fmt.Sprintf("%s contains an empty table name", name)}}
+		}
+	}
+
+	return nil
+}
+
+// Lint checks rules, in order of precedence, for problems. Query IDs are checked against queries, pass nil to skip
+// that check. Findings are ordered by rule.
+func Lint(rules []RedisCommon.Rule, queries []*RedisCommon.Query) []Finding {
+	findings := make([]Finding, 0)
+	conditions := make([]RuleMatcher.Conditions, len(rules))
+	for i, r := range rules {
+		conditions[i] = r.Conditions()
+	}
+
+	knownIds := make(map[string]bool)
+	for _, q := range queries {
+		knownIds[q.Id] = true
+	}
+
+	byHash := make(map[uint64][]int)
+	for i, r := range rules {
+		if err := RedisCommon.ValidateTtl(r.Ttl, true); errors.Is(err, RedisCommon.ErrTtlOutOfBounds) {
+			findings = append(findings, Finding{i, Error, TtlOutOfPolicy, err.Error()})
+		} else if err != nil {
+			findings = append(findings, Finding{i, Error, InvalidTtl, err.Error()})
+		}
+
+		if r.Regex != nil {
+			err := RuleMatcher.CheckRegex(*r.Regex)
+			if errors.Is(err, RuleMatcher.ErrInvalidRegex) {
+				findings = append(findings, Finding{i, Error, InvalidRegex, err.Error()})
+			} else if err != nil {
+				findings = append(findings, Finding{i, Warning, InvalidRegex, err.Error()})
+			}
+		}
+
+		findings = append(findings, checkTableList(i, "tables", r.Tables)...)
+		findings = append(findings, checkTableList(i, "tables-any", r.TablesAny)...)
+		findings = append(findings, checkTableList(i, "tables-all", r.TablesAll)...)
+
+		if queries != nil {
+			for _, id := range r.QueryIds {
+				if !knownIds[id] {
+					findings = append(findings, Finding{i, Warning, UnknownQueryId, fmt.Sprintf("query ID %s has not been seen by Smart Cache", id)})
+				}
+			}
+		}
+
+		if RuleMatcher.NeverMatches(conditions[i]) {
+			findings = append(findings, Finding{i, Warning, NeverMatches, "the conditions contradict each other, the rule can never match"})
+			continue
+		}
+
+		// Rule.Hash ignores which condition a list belongs to, so candidates are confirmed with Equal.
+		h := r.Hash()
+		duplicate := false
+		for _, j := range byHash[h] {
+			if rules[j].Equal(r) {
+				findings = append(findings, Finding{i, Warning, Duplicate, fmt.Sprintf("duplicate of rule %d", j)})
+				duplicate = true
+				break
+			}
+		}
+		byHash[h] = append(byHash[h], i)
+		if duplicate {
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			if !RuleMatcher.NeverMatches(conditions[j]) && RuleMatcher.Covers(conditions[j], conditions[i]) {
+				findings = append(findings, Finding{i, Warning, Shadowed,
+					fmt.Sprintf("never applies, rule %d (%s) matches every query it matches", j, rules[j].Summary())})
+				break
+			}
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,96 @@
+package RuleLint
+
+import (
+	"reflect"
+	"smart-cache-cli/RedisCommon"
+	"testing"
+	"time"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// check is a finding without its message.
+type check struct {
+	Index    int
+	Severity Severity
+	Check    string
+}
+
+func TestLint(t *testing.T) {
+	RedisCommon.SetTtlPolicy(RedisCommon.TtlPolicy{Min: RedisCommon.Ttl(time.Minute), Max: RedisCommon.Ttl(24 * time.Hour)})
+	t.Cleanup(func() { RedisCommon.SetTtlPolicy(RedisCommon.TtlPolicy{}) })
+
+	queries := []*RedisCommon.Query{{Id: "q1"}, {Id: "q2"}}
+	orders := []string{"orders"}
+
+	tests := []struct {
+		name    string
+		rules   []RedisCommon.Rule
+		queries []*RedisCommon.Query
+		want    []check
+	}{
+		{"valid rules", []RedisCommon.Rule{
+			{TablesAny: orders, Ttl: "5m"},
+			{QueryIds: []string{"q1"}, Ttl: "0s"},
+			{Ttl: "1h"},
+		}, queries, []check{}},
+		{"unparsable ttl", []RedisCommon.Rule{{TablesAny: orders, Ttl: "5 minutes"}}, nil,
+			[]check{{0, Error, InvalidTtl}}},
+		{"ttl shorter than the policy", []RedisCommon.Rule{{TablesAny: orders, Ttl: "10s"}}, nil,
+			[]check{{0, Error, TtlOutOfPolicy}}},
+		{"ttl longer than the policy", []RedisCommon.Rule{{TablesAny: orders, Ttl: "2d"}}, nil,
+			[]check{{0, Error, TtlOutOfPolicy}}},
+		{"invalid regex", []RedisCommon.Rule{{Regex: strPtr("SELECT ("), Ttl: "5m"}}, nil,
+			[]check{{0, Error, InvalidRegex}}},
+		{"regex the CLI cannot evaluate", []RedisCommon.Rule{{Regex: strPtr("(?=SELECT).*"), Ttl: "5m"}}, nil,
+			[]check{{0, Warning, InvalidRegex}}},
+		{"empty table list", []RedisCommon.Rule{{TablesAll: []string{}, Ttl: "5m"}}, nil,
+			[]check{{0, Warning, EmptyTables}}},
+		{"empty table name", []RedisCommon.Rule{{TablesAny: []string{"orders", " "}, Ttl: "5m"}}, nil,
+			[]check{{0, Warning, EmptyTables}}},
+		{"contradicting conditions", []RedisCommon.Rule{{Tables: orders, TablesAny: []string{"customers"}, Ttl: "5m"}}, nil,
+			[]check{{0, Warning, NeverMatches}}},
+		{"regex that matches no SQL", []RedisCommon.Rule{{Regex: strPtr("SELECT [^\\s\\S]"), Ttl: "5m"}}, nil,
+			[]check{{0, Warning, NeverMatches}}},
+		{"shadowed rule", []RedisCommon.Rule{
+			{TablesAny: orders, Ttl: "5m"},
+			{Tables: orders, Ttl: "1h"},
+		}, nil, []check{{1, Warning, Shadowed}}},
+		{"a rule that never matches shadows nothing", []RedisCommon.Rule{
+			{TablesAny: []string{}, Ttl: "5m"},
+			{TablesAny: orders, Ttl: "1h"},
+		}, nil, []check{{0, Warning, EmptyTables}, {0, Warning, NeverMatches}}},
+		{"duplicate rule", []RedisCommon.Rule{
+			{TablesAny: orders, Ttl: "5m"},
+			{TablesAny: orders, Ttl: "5m"},
+		}, nil, []check{{1, Warning, Duplicate}}},
+		{"unknown query id", []RedisCommon.Rule{{QueryIds: []string{"q1", "q9"}, Ttl: "5m"}}, queries,
+			[]check{{0, Warning, UnknownQueryId}}},
+		{"query ids are not checked without queries", []RedisCommon.Rule{{QueryIds: []string{"q9"}, Ttl: "5m"}}, nil,
+			[]check{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Lint(tt.rules, tt.queries)
+			got := make([]check, len(findings))
+			for i, f := range findings {
+				got[i] = check{f.Index, f.Severity, f.Check}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %+v, want %+v", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	if HasErrors([]Finding{{0, Warning, Shadowed, ""}}) {
+		t.Error("HasErrors() = true for warnings only, want false")
+	}
+	if !HasErrors([]Finding{{0, Warning, Shadowed, ""}, {1, Error, InvalidTtl, ""}}) {
+		t.Error("HasErrors() = false with an error, want true")
+	}
+}
@@ -1,10 +1,12 @@
 package RuleList
 
 import (
+	"fmt"
 	"smart-cache-cli/BulkUpdateConfirmation"
 	"smart-cache-cli/ConfirmationDialog"
 	"smart-cache-cli/RedisCommon"
 	"smart-cache-cli/RuleDialog"
+	"smart-cache-cli/RuleLint"
 	"smart-cache-cli/SortDialog"
 	"smart-cache-cli/util"
 	"strings"
@@ -12,23 +14,27 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/evertras/bubble-table/table"
-	"github.com/redis/go-redis/v9"
 )
 
 type Model struct {
 	parentModel               tea.Model
 	table                     table.Model
 	rules                     []RedisCommon.Rule
-	backupRules               map[uint64]*RedisCommon.Rule
+	baseIndexes               []int
 	Selection                 int
-	rdb                       *redis.Client
+	store                     RedisCommon.SmartCacheStore
 	committed                 bool
 	sortColumn                string
 	sortDirection             SortDialog.Direction
 	indexesWithPendingUpdates []int
 	indexesWithPendingDeletes []int
 	indexesWithNewRules       []int
-	applicationName           string
+	baseId                    string
+	baseRules                 []RedisCommon.Rule
+	notice                    string
+	showLint                  bool
+	lintQueries               []*RedisCommon.Query
+	err                       error
 }
 
 var (
@@ -57,10 +63,87 @@ func (m Model) Init() tea.Cmd {
 	return nil
 }
 
+// remapRows moves the pending edits along with their rows when rows change position.
+func (m Model) remapRows(newRow func(row int) int) {
+	for _, indexes := range [][]int{m.indexesWithNewRules, m.indexesWithPendingUpdates, m.indexesWithPendingDeletes} {
+		for i := range indexes {
+			indexes[i] = newRow(indexes[i])
+		}
+	}
+}
+
+// sortedByPrecedence reports whether the rules are listed in order of precedence, the only order they can be moved in.
+func (m Model) sortedByPrecedence() bool {
+	return m.sortColumn == "RowId" && m.sortDirection == SortDialog.Ascending
+}
+
+// moveRow moves a rule like MoveRow does, unless the rules are sorted in another order than precedence.
+func (m Model) moveRow(from int, to int) Model {
+	if !m.sortedByPrecedence() {
+		m.notice = "Sort the rules by Rule Precedence, ascending, to move them."
+		return m
+	}
+	return m.MoveRow(from, to)
+}
+
+// MoveRow moves a rule to another position in the order of precedence, keeping the highlight on it.
+func (m Model) MoveRow(from int, to int) Model {
+	if from < 0 || from >= len(m.rules) || to < 0 || to >= len(m.rules) || from == to {
+		return m
+	}
+
+	rule, baseIndex := m.rules[from], m.baseIndexes[from]
+	m.rules = util.Remove(m.rules, from)
+	m.baseIndexes = util.Remove(m.baseIndexes, from)
+	m.rules = append(m.rules[:to], append([]RedisCommon.Rule{rule}, m.rules[to:]...)...)
+	m.baseIndexes = append(m.baseIndexes[:to], append([]int{baseIndex}, m.baseIndexes[to:]...)...)
+
+	m.remapRows(func(row int) int {
+		switch {
+		case row == from:
+			return to
+		case from < to && row > from && row <= to:
+			return row - 1
+		case to < from && row >= to && row < from:
+			return row + 1
+		default:
+			return row
+		}
+	})
+
+	m.table = m.RefreshRows()
+	if m.sortedByPrecedence() {
+		m.table = m.table.WithHighlightedRow(to)
+	}
+	return m
+}
+
+// originalPosition is where a moved rule goes back to, after the rules that preceded it when the rules were loaded.
+func (m Model) originalPosition(rowId int) int {
+	target, pos := 0, 0
+	for i, baseIndex := range m.baseIndexes {
+		if i == rowId {
+			continue
+		}
+		if baseIndex >= 0 && baseIndex < m.baseIndexes[rowId] {
+			target = pos + 1
+		}
+		pos++
+	}
+	return target
+}
+
 func (m Model) DeleteRow(rowId int) Model {
 	if contains(rowId, m.indexesWithNewRules) {
-		util.Remove(m.indexesWithNewRules, rowId)
-		util.Remove(m.rules, rowId)
+		m.indexesWithNewRules = util.Remove(m.indexesWithNewRules, indexOf(rowId, m.indexesWithNewRules))
+		m.rules = util.Remove(m.rules, rowId)
+		m.baseIndexes = util.Remove(m.baseIndexes, rowId)
+		m.remapRows(func(row int) int {
+			if row > rowId {
+				return row - 1
+			}
+			return row
+		})
 	} else {
 		m.indexesWithPendingDeletes = append(m.indexesWithPendingDeletes, rowId)
 	}
@@ -86,7 +169,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.parentModel, _ = m.parentModel.Update(ConfirmationDialog.ConfirmationMessage{ConfirmedUpdate: true})
 			return m.parentModel, nil
 		case "n":
-			return RuleDialog.New(m, m.rdb, nil, false, m.applicationName, RedisCommon.Unknown), nil
+			return RuleDialog.New(m, m.store, nil, false), nil
 		case "r":
 			idxInDelete := indexOf(rowId, m.indexesWithPendingDeletes)
 			if idxInDelete >= 0 {
@@ -96,45 +179,66 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			idxOfEdit := indexOf(rowId, m.indexesWithPendingUpdates)
 			if idxOfEdit >= 0 {
-				h := m.rules[rowId].Hash()
-				backUp, ok := m.backupRules[h]
-				if ok {
-					m.rules[rowId] = *backUp
-				}
+				m.rules[rowId] = m.baseRules[m.baseIndexes[rowId]]
 				m.indexesWithPendingUpdates = util.Remove(m.indexesWithPendingUpdates, idxOfEdit)
 				m.table = m.RefreshRows()
 			}
+
+			if rowId >= 0 && RedisCommon.MovedRules(m.rowOrder())[rowId] {
+				m = m.MoveRow(rowId, m.originalPosition(rowId))
+			}
 			return m, nil
+		case "K", tea.KeyShiftUp.String():
+			return m.moveRow(rowId, rowId-1), nil
+		case "J", tea.KeyShiftDown.String():
+			return m.moveRow(rowId, rowId+1), nil
+		case "t":
+			return m.moveRow(rowId, 0), nil
+		case "B":
+			return m.moveRow(rowId, len(m.rules)-1), nil
+		case "s":
+			return SortDialog.New([]string{"Rule Precedence", "TTL"}, m), nil
 		case "d":
-			m = m.DeleteRow(rowId)
+			if rowId >= 0 {
+				m = m.DeleteRow(rowId)
+			}
 			return m, nil
 		case "c":
-			rulesToAdd := make([]RedisCommon.Rule, len(m.indexesWithNewRules))
-			for i, idx := range m.indexesWithNewRules {
-				rulesToAdd[i] = m.rules[idx]
-			}
-
-			rulesToUpdate := make(map[int]RedisCommon.Rule)
-			for _, idx := range m.indexesWithPendingUpdates {
-				rulesToUpdate[idx+len(m.indexesWithNewRules)] = m.rules[idx]
-			}
-
-			rulesToDelete := make(map[int]RedisCommon.Rule)
-			for _, idx := range m.indexesWithPendingDeletes {
-				rulesToDelete[idx+len(m.indexesWithNewRules)] = m.rules[idx]
+			return m.confirmationDialog(), nil
+		case "l":
+			m = m.ReloadRules()
+			m.table = m.RefreshRows()
+			return m, nil
+		case "L":
+			m.showLint = !m.showLint
+			if m.showLint {
+				m.lintQueries, m.err = m.store.GetQueries()
 			}
-			confirmationDialog := BulkUpdateConfirmation.New(m, rulesToAdd, rulesToUpdate, rulesToDelete, m.rdb, m.applicationName)
-			return confirmationDialog, nil
+			return m, nil
 
 		case tea.KeyTab.String(), tea.KeySpace.String(), tea.KeyEnter.String(), "e":
 			if rowId >= 0 {
 				// pop open editor
 				rule := m.rules[rowId]
-				return RuleDialog.New(m, m.rdb, &rule, false, m.applicationName, rule.GetType()), nil
+				return RuleDialog.New(m, m.store, &rule, false), nil
 			}
 		}
 	case BulkUpdateConfirmation.BulkConfirmationMessage:
+		if msg.Reload {
+			m = m.ReloadRules()
+			m.table = m.RefreshRows()
+			if m.err != nil || !m.hasPendingEdits() {
+				return m, nil
+			}
+			return m.confirmationDialog(), nil
+		}
+		if msg.Err != nil {
+			m.err = msg.Err
+			return m, nil
+		}
 		if msg.ConfirmedUpdate {
+			m.err = nil
+			m.notice = ""
 			m.table = m.table.WithStaticFooter(msg.Message)
 			m.indexesWithNewRules = m.indexesWithNewRules[:0]
 			m.indexesWithPendingUpdates = m.indexesWithPendingUpdates[:0]
@@ -143,17 +247,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.table = m.RefreshRows()
 			return m, nil
 		}
-	case RuleDialog.RuleMsg:
-		if rowId >= 0 && m.rules[rowId].Equal(msg.Rule) {
-			return m, nil
+	case SortDialog.SortMessage:
+		m.sortColumn, m.sortDirection = msg.Choice, msg.Direction
+		if msg.Choice == "Rule Precedence" {
+			m.sortColumn = "RowId"
+		}
+		columns := RedisCommon.GetColumnsOfRule(m.sortColumn, m.sortDirection)
+		if m.sortDirection == SortDialog.Descending {
+			m.table = m.table.WithColumns(columns).SortByDesc(RedisCommon.SortKey(m.sortColumn))
+		} else {
+			m.table = m.table.WithColumns(columns).SortByAsc(RedisCommon.SortKey(m.sortColumn))
 		}
+		return m, nil
+	case RuleDialog.RuleMsg:
+		if msg.IsNew {
+			m.rules = append([]RedisCommon.Rule{msg.Rule}, m.rules...)
+			m.baseIndexes = append([]int{-1}, m.baseIndexes...)
+			m.remapRows(func(row int) int { return row + 1 })
+			m.indexesWithNewRules = append(m.indexesWithNewRules, 0)
+		} else {
+			if rowId < 0 || m.rules[rowId].Equal(msg.Rule) {
+				return m, nil
+			}
 
-		if !msg.IsNew {
-			h := msg.Rule.Hash()
-			cpy := m.rules[rowId]
-			m.backupRules[h] = &cpy
 			m.rules[rowId] = msg.Rule
-			if !contains(rowId, m.indexesWithPendingUpdates) {
+			if m.baseIndexes[rowId] >= 0 && !contains(rowId, m.indexesWithPendingUpdates) {
 				m.indexesWithPendingUpdates = append(m.indexesWithPendingUpdates, rowId)
 			}
 
@@ -161,20 +279,6 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if idxInDelete >= 0 {
 				m.indexesWithPendingDeletes = util.Remove(m.indexesWithPendingDeletes, idxInDelete)
 			}
-		} else {
-			m.rules = append([]RedisCommon.Rule{msg.Rule}, m.rules...)
-			for i := range m.indexesWithNewRules {
-				m.indexesWithNewRules[i]++
-			}
-			m.indexesWithNewRules = append(m.indexesWithNewRules, 0)
-
-			for i := range m.indexesWithPendingDeletes {
-				m.indexesWithPendingDeletes[i]++
-			}
-
-			for i := range m.indexesWithPendingUpdates {
-				m.indexesWithPendingUpdates[i]++
-			}
 		}
 
 		m.table = m.RefreshRows()
@@ -193,8 +297,20 @@ func (m Model) View() string {
 	body.WriteString("press [ENTER] to edit a rule\n")
 	body.WriteString("press 'n' to create a rule\n")
 	body.WriteString("press 'd' to delete a rule\n")
+	body.WriteString("press 'K'/'J' or [SHIFT+↑/↓] to move a rule up/down, 't'/'B' to move it to the top/bottom\n")
+	body.WriteString("press 's' to change sorting\n")
+	body.WriteString("press 'r' to revert the pending updates of a rule\n")
 	body.WriteString("press 'c' to commit rule updates\n")
+	body.WriteString("press 'l' to reload the rules, keeping pending updates\n")
+	body.WriteString("press 'L' to show or hide problems found in the rules\n")
 	body.WriteString(m.table.View())
+	if m.notice != "" {
+		body.WriteString("\n" + m.notice)
+	}
+	if m.showLint {
+		body.WriteString("\n" + m.lintView())
+	}
+	body.WriteString(util.StatusLine(m.err))
 
 	return body.String()
 }
@@ -214,6 +330,7 @@ func contains(i int, s []int) bool {
 }
 
 func (m Model) RefreshRows() table.Model {
+	moved := RedisCommon.MovedRules(m.rowOrder())
 	rows := make([]table.Row, len(m.rules))
 	for i, r := range m.rules {
 		if contains(i, m.indexesWithPendingUpdates) {
@@ -222,6 +339,10 @@ func (m Model) RefreshRows() table.Model {
 			rows[i] = r.AsRow(i).WithStyle(lipgloss.NewStyle().Background(lipgloss.Color("9")).Foreground(lipgloss.Color("0")))
 		} else if contains(i, m.indexesWithNewRules) {
 			rows[i] = r.AsRow(i).WithStyle(lipgloss.NewStyle().Background(lipgloss.Color("10")).Foreground(lipgloss.Color("0")))
+		} else if moved[i] {
+			rows[i] = r.AsRow(i).WithStyle(lipgloss.NewStyle().Background(lipgloss.Color("14")).Foreground(lipgloss.Color("0")))
+		} else if r.DisablesCaching() {
+			rows[i] = r.AsRow(i).WithStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("13")))
 		} else {
 			rows[i] = r.AsRow(i)
 		}
@@ -231,12 +352,168 @@ func (m Model) RefreshRows() table.Model {
 }
 
 func (m Model) FreshRulesFromRedis() Model {
-	m.rules, _ = RedisCommon.GetRules(m.rdb, m.applicationName)
+	ruleSet, err := m.store.GetRuleSet()
+	m.err = err
+	if err == nil {
+		m.rules = ruleSet.Rules
+		m.baseIndexes = baseIndexes(ruleSet.Rules)
+		m.baseId = ruleSet.Id
+		m.baseRules = make([]RedisCommon.Rule, len(ruleSet.Rules))
+		copy(m.baseRules, ruleSet.Rules)
+	}
 	return m
 }
 
-func New(parentModel tea.Model, rdb *redis.Client, applicationName string) Model {
-	rules, _ := RedisCommon.GetRules(rdb, applicationName)
+func baseIndexes(rules []RedisCommon.Rule) []int {
+	indexes := make([]int, len(rules))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+// rowOrder lists every row, including the pending deletes, with the index of its rule in the loaded rules.
+func (m Model) rowOrder() []RedisCommon.OrderedRule {
+	order := make([]RedisCommon.OrderedRule, len(m.rules))
+	for i, r := range m.rules {
+		order[i] = RedisCommon.OrderedRule{BaseIndex: m.baseIndexes[i], Rule: r}
+	}
+	return order
+}
+
+// pendingOrder is the order of the rules once the pending updates are committed, which is the shape UpdateRules
+// expects.
+func (m Model) pendingOrder() []RedisCommon.OrderedRule {
+	order := make([]RedisCommon.OrderedRule, 0, len(m.rules))
+	for i, o := range m.rowOrder() {
+		if !contains(i, m.indexesWithPendingDeletes) {
+			order = append(order, o)
+		}
+	}
+	return order
+}
+
+func (m Model) hasPendingEdits() bool {
+	return RedisCommon.HasRuleChanges(m.baseRules, m.pendingOrder())
+}
+
+func (m Model) confirmationDialog() BulkUpdateConfirmation.Model {
+	return BulkUpdateConfirmation.New(m, m.baseId, m.baseRules, m.pendingOrder(), m.store)
+}
+
+// ReloadRules loads the latest rules and re-applies the pending edits on top of them, following each rule to its
+// new position. Edits to rules that were changed or removed in the meantime are dropped and listed in the notice
+// together with what changed.
+func (m Model) ReloadRules() Model {
+	ruleSet, err := m.store.GetRuleSet()
+	if err != nil {
+		m.err = err
+		return m
+	}
+	m.err = nil
+
+	changes := RedisCommon.DiffRules(m.baseRules, ruleSet.Rules)
+	order := m.rowOrder()
+	moved := RedisCommon.MovedRules(order)
+	rebased, sources, dropped := RedisCommon.RebaseRuleOrder(m.baseRules, ruleSet.Rules, order)
+
+	conflicts := make([]string, 0)
+	for _, row := range dropped {
+		var edit string
+		switch {
+		case contains(row, m.indexesWithPendingUpdates):
+			edit = "update"
+		case contains(row, m.indexesWithPendingDeletes):
+			edit = "delete"
+		case moved[row]:
+			edit = "move"
+		default:
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("dropped %s of rule %d (%s): it was changed or removed concurrently",
+			edit, m.baseIndexes[row], m.baseRules[m.baseIndexes[row]].Summary()))
+	}
+
+	newRules, updates, deletes := make([]int, 0), make([]int, 0), make([]int, 0)
+	m.rules = make([]RedisCommon.Rule, len(rebased))
+	m.baseIndexes = make([]int, len(rebased))
+	for i, o := range rebased {
+		m.rules[i] = o.Rule
+		m.baseIndexes[i] = o.BaseIndex
+		switch src := sources[i]; {
+		case src < 0:
+		case contains(src, m.indexesWithNewRules):
+			newRules = append(newRules, i)
+		case contains(src, m.indexesWithPendingDeletes):
+			deletes = append(deletes, i)
+		case contains(src, m.indexesWithPendingUpdates):
+			updates = append(updates, i)
+		}
+	}
+	m.indexesWithNewRules, m.indexesWithPendingUpdates, m.indexesWithPendingDeletes = newRules, updates, deletes
+
+	m.baseId = ruleSet.Id
+	m.baseRules = make([]RedisCommon.Rule, len(ruleSet.Rules))
+	copy(m.baseRules, ruleSet.Rules)
+
+	notice := strings.Builder{}
+	if RedisCommon.HasChanges(changes) {
+		notice.WriteString("Reloaded rules, changes made since they were last loaded:\n")
+		notice.WriteString(RedisCommon.FormatRuleDiff(changes))
+	} else {
+		notice.WriteString("Reloaded rules, no changes since they were last loaded.\n")
+	}
+	for _, c := range conflicts {
+		notice.WriteString(c + "\n")
+	}
+	m.notice = notice.String()
+
+	return m
+}
+
+// lintFindings lints the rules as they will be once the pending updates are committed, with the index of each
+// finding pointing at the rule's row in the table.
+func (m Model) lintFindings() []RuleLint.Finding {
+	rules := make([]RedisCommon.Rule, 0, len(m.rules))
+	rows := make([]int, 0, len(m.rules))
+	for i, r := range m.rules {
+		if !contains(i, m.indexesWithPendingDeletes) {
+			rules = append(rules, r)
+			rows = append(rows, i)
+		}
+	}
+
+	findings := RuleLint.Lint(rules, m.lintQueries)
+	for i := range findings {
+		findings[i].Index = rows[findings[i].Index]
+	}
+	return findings
+}
+
+func (m Model) lintView() string {
+	findings := m.lintFindings()
+	if len(findings) == 0 {
+		return "No problems found in the rules.\n"
+	}
+
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+
+	body := strings.Builder{}
+	body.WriteString("Problems found in the rules, by Rule Precedence:\n")
+	for _, f := range findings {
+		style := warningStyle
+		if f.Severity == RuleLint.Error {
+			style = errorStyle
+		}
+		body.WriteString(style.Render(f.String()) + "\n")
+	}
+	return body.String()
+}
+
+func New(parentModel tea.Model, store RedisCommon.SmartCacheStore) Model {
+	ruleSet, err := store.GetRuleSet()
+	rules := ruleSet.Rules
 	rows := make([]table.Row, len(rules))
 	for i, r := range rules {
 		rows[i] = r.AsRow(i)
@@ -251,11 +528,15 @@ func New(parentModel tea.Model, rdb *redis.Client, applicationName string) Model
 			WithPageSize(10).
 			SortByAsc("RowId").
 			WithTargetWidth(200),
-		rules:           rules,
-		parentModel:     parentModel,
-		rdb:             rdb,
-		backupRules:     make(map[uint64]*RedisCommon.Rule),
-		applicationName: applicationName,
+		rules:         rules,
+		baseIndexes:   baseIndexes(rules),
+		sortColumn:    "RowId",
+		sortDirection: SortDialog.Ascending,
+		parentModel:   parentModel,
+		store:         store,
+		baseId:        ruleSet.Id,
+		baseRules:     append([]RedisCommon.Rule(nil), rules...),
+		err:           err,
 	}
 
 	return model
@@ -0,0 +1,122 @@
+package RuleList
+
+import (
+	"errors"
+	"smart-cache-cli/RedisCommon"
+	"smart-cache-cli/RuleDialog"
+	"strings"
+	"testing"
+)
+
+func ttlRule(table string, ttl string) RedisCommon.Rule {
+	return RedisCommon.Rule{TablesAny: []string{table}, Ttl: ttl}
+}
+
+func newModel(rules ...RedisCommon.Rule) Model {
+	return New(nil, RedisCommon.NewMemoryStore("test", nil, rules))
+}
+
+func TestRuleMsg(t *testing.T) {
+	a, b := ttlRule("a", "5m"), ttlRule("b", "1h")
+
+	tests := []struct {
+		name        string
+		msg         RuleDialog.RuleMsg
+		wantRules   []RedisCommon.Rule
+		wantNew     []int
+		wantUpdates []int
+	}{
+		{
+			name:      "a new rule identical to the highlighted rule is added",
+			msg:       RuleDialog.RuleMsg{Rule: a, IsNew: true},
+			wantRules: []RedisCommon.Rule{a, a, b},
+			wantNew:   []int{0},
+		},
+		{
+			name:      "an edit that changes nothing is ignored",
+			msg:       RuleDialog.RuleMsg{Rule: a},
+			wantRules: []RedisCommon.Rule{a, b},
+		},
+		{
+			name:        "an edit that changes the rule is pending",
+			msg:         RuleDialog.RuleMsg{Rule: ttlRule("a", "1m")},
+			wantRules:   []RedisCommon.Rule{ttlRule("a", "1m"), b},
+			wantUpdates: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, _ := newModel(a, b).Update(tt.msg)
+			m := updated.(Model)
+
+			if len(m.rules) != len(tt.wantRules) {
+				t.Fatalf("rules = %v, want %v", m.rules, tt.wantRules)
+			}
+			for i := range tt.wantRules {
+				if !m.rules[i].Equal(tt.wantRules[i]) {
+					t.Errorf("rule %d = %v, want %v", i, m.rules[i], tt.wantRules[i])
+				}
+			}
+			if len(m.indexesWithNewRules) != len(tt.wantNew) || len(tt.wantNew) > 0 && m.indexesWithNewRules[0] != tt.wantNew[0] {
+				t.Errorf("new rows = %v, want %v", m.indexesWithNewRules, tt.wantNew)
+			}
+			if len(m.indexesWithPendingUpdates) != len(tt.wantUpdates) || len(tt.wantUpdates) > 0 && m.indexesWithPendingUpdates[0] != tt.wantUpdates[0] {
+				t.Errorf("updated rows = %v, want %v", m.indexesWithPendingUpdates, tt.wantUpdates)
+			}
+		})
+	}
+}
+
+func TestReloadRulesAfterStaleCommit(t *testing.T) {
+	a, b, c := ttlRule("a", "5m"), ttlRule("b", "1h"), ttlRule("c", "1d")
+	added, changedB, changedC := ttlRule("new", "1m"), ttlRule("b", "2h"), ttlRule("c", "2d")
+	store := RedisCommon.NewMemoryStore("test", nil, []RedisCommon.Rule{a, b, c})
+
+	updated, _ := New(nil, store).Update(RuleDialog.RuleMsg{Rule: added, IsNew: true})
+	m := updated.(Model)
+	m.rules[2], m.rules[3] = changedB, changedC
+	m.indexesWithPendingUpdates = []int{2, 3}
+
+	// Someone else adds a rule at the top and changes b before the pending edits are committed.
+	if _, err := store.ReplaceRules(m.baseId, []RedisCommon.Rule{ttlRule("x", "1s"), a, ttlRule("b", "3h"), c}); err != nil {
+		t.Fatalf("ReplaceRules() error = %v", err)
+	}
+
+	err := store.UpdateRules(m.baseId, m.pendingOrder())
+	var stale *RedisCommon.StaleRulesError
+	if !errors.As(err, &stale) {
+		t.Fatalf("UpdateRules() error = %v, want a *StaleRulesError", err)
+	}
+
+	m = m.ReloadRules()
+	if m.err != nil {
+		t.Fatalf("ReloadRules() error = %v", m.err)
+	}
+
+	want := []RedisCommon.Rule{added, ttlRule("x", "1s"), a, ttlRule("b", "3h"), changedC}
+	if len(m.rules) != len(want) {
+		t.Fatalf("rules = %v, want %v", m.rules, want)
+	}
+	for i := range want {
+		if !m.rules[i].Equal(want[i]) {
+			t.Errorf("rule %d = %v, want %v", i, m.rules[i], want[i])
+		}
+	}
+	if len(m.indexesWithNewRules) != 1 || m.indexesWithNewRules[0] != 0 {
+		t.Errorf("new rows = %v, want [0]", m.indexesWithNewRules)
+	}
+	if len(m.indexesWithPendingUpdates) != 1 || m.indexesWithPendingUpdates[0] != 4 {
+		t.Errorf("updated rows = %v, want [4]", m.indexesWithPendingUpdates)
+	}
+	if !strings.Contains(m.notice, "dropped update of rule 1") {
+		t.Errorf("notice = %q, want it to list the dropped update of rule 1", m.notice)
+	}
+
+	if err := store.UpdateRules(m.baseId, m.pendingOrder()); err != nil {
+		t.Fatalf("UpdateRules() after reloading error = %v", err)
+	}
+	if rules, _ := store.GetRules(); len(rules) != len(want) || !rules[4].Equal(changedC) || !rules[0].Equal(added) {
+		t.Errorf("committed rules = %v, want %v", rules, want)
+	}
+}
@@ -0,0 +1,320 @@
+// Package RuleMatcher evaluates caching rules against queries the way Redis Smart Cache does, so that the CLI can
+// show which rule applies to a query without asking the server.
+//
+// A rule matches a query when every condition it sets holds, and a rule without conditions matches every query.
+// Rules are evaluated in order and the first rule that matches applies. The conditions are:
+//
+//   - tables: the set of tables of the query is exactly the given set
+//   - tablesAny: the query uses at least one of the given tables
+//   - tablesAll: the query uses every one of the given tables, and possibly others
+//   - regex: the SQL of the query matches the Java regular expression in full
+//   - queryIds: the ID of the query is one of the given IDs
+package RuleMatcher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Conditions are the match conditions of a rule, a nil field is not set.
+type Conditions struct {
+	Tables    []string
+	TablesAny []string
+	TablesAll []string
+	Regex     *string
+	QueryIds  []string
+}
+
+// IsEmpty reports whether no condition is set, in which case the rule matches every query.
+func (c Conditions) IsEmpty() bool {
+	return c.Tables == nil && c.TablesAny == nil && c.TablesAll == nil && c.Regex == nil && c.QueryIds == nil
+}
+
+// Subject is the query a rule is evaluated against.
+type Subject struct {
+	Id     string
+	Sql    string
+	Tables []string
+}
+
+// Condition names as they appear in explanations, matching the rule properties of the config stream.
+const (
+	TablesCondition    = "tables"
+	TablesAnyCondition = "tables-any"
+	TablesAllCondition = "tables-all"
+	RegexCondition     = "regex"
+	QueryIdsCondition  = "query-ids"
+	NoCondition        = "none"
+)
+
+// ConditionResult is the outcome of evaluating one condition of a rule.
+type ConditionResult struct {
+	Condition string
+	Matched   bool
+	Reason    string
+}
+
+// Explanation is the outcome of evaluating a rule, with the result of each of its conditions in evaluation order.
+type Explanation struct {
+	Matched    bool
+	Conditions []ConditionResult
+}
+
+// Reason summarizes why a rule did or did not match on one line.
+func (e Explanation) Reason() string {
+	reasons := make([]string, 0, len(e.Conditions))
+	for _, c := range e.Conditions {
+		if e.Matched || !c.Matched {
+			reasons = append(reasons, c.Reason)
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// ParseTables splits the comma-delimited table list Smart Cache stores for a query.
+func ParseTables(tables string) []string {
+	res := make([]string, 0)
+	for _, t := range strings.Split(tables, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+func toSet(s []string) map[string]bool {
+	set := make(map[string]bool, len(s))
+	for _, v := range s {
+		set[v] = true
+	}
+	return set
+}
+
+func formatSet(s []string) string {
+	return "[" + strings.Join(s, ",") + "]"
+}
+
+func evalTables(tables []string, subject Subject) ConditionResult {
+	want, got := toSet(tables), toSet(subject.Tables)
+	extra := make([]string, 0)
+	for _, t := range subject.Tables {
+		if !want[t] {
+			extra = append(extra, t)
+		}
+	}
+	missing := make([]string, 0)
+	for _, t := range tables {
+		if !got[t] {
+			missing = append(missing, t)
+		}
+	}
+
+	res := ConditionResult{Condition: TablesCondition, Matched: len(extra) == 0 && len(missing) == 0}
+	switch {
+	case res.Matched:
+		res.Reason = fmt.Sprintf("tables %s are exactly %s", formatSet(subject.Tables), formatSet(tables))
+	case len(missing) > 0:
+		res.Reason = fmt.Sprintf("tables %s do not include %s", formatSet(subject.Tables), formatSet(missing))
+	default:
+		res.Reason = fmt.Sprintf("tables %s also include %s", formatSet(subject.Tables), formatSet(extra))
+	}
+	return res
+}
+
+func evalTablesAny(tablesAny []string, subject Subject) ConditionResult {
+	want := toSet(tablesAny)
+	for _, t := range subject.Tables {
+		if want[t] {
+			return ConditionResult{Condition: TablesAnyCondition, Matched: true,
+				Reason: fmt.Sprintf("table %s is one of %s", t, formatSet(tablesAny))}
+		}
+	}
+	return ConditionResult{Condition: TablesAnyCondition,
+		Reason: fmt.Sprintf("none of tables %s is one of %s", formatSet(subject.Tables), formatSet(tablesAny))}
+}
+
+func evalTablesAll(tablesAll []string, subject Subject) ConditionResult {
+	got := toSet(subject.Tables)
+	missing := make([]string, 0)
+	for _, t := range tablesAll {
+		if !got[t] {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		return ConditionResult{Condition: TablesAllCondition,
+			Reason: fmt.Sprintf("tables %s do not include %s", formatSet(subject.Tables), formatSet(missing))}
+	}
+	return ConditionResult{Condition: TablesAllCondition, Matched: true,
+		Reason: fmt.Sprintf("tables %s include all of %s", formatSet(subject.Tables), formatSet(tablesAll))}
+}
+
+func evalRegex(regex string, subject Subject) ConditionResult {
+	matched, err := MatchRegex(regex, subject.Sql)
+	if err != nil {
+		return ConditionResult{Condition: RegexCondition, Reason: err.Error()}
+	}
+	if matched {
+		return ConditionResult{Condition: RegexCondition, Matched: true, Reason: fmt.Sprintf("SQL matches regex '%s'", regex)}
+	}
+	return ConditionResult{Condition: RegexCondition, Reason: fmt.Sprintf("SQL does not match regex '%s' in full", regex)}
+}
+
+func evalQueryIds(queryIds []string, subject Subject) ConditionResult {
+	for _, id := range queryIds {
+		if id == subject.Id {
+			return ConditionResult{Condition: QueryIdsCondition, Matched: true,
+				Reason: fmt.Sprintf("query ID %s is one of %s", subject.Id, formatSet(queryIds))}
+		}
+	}
+	return ConditionResult{Condition: QueryIdsCondition,
+		Reason: fmt.Sprintf("query ID %s is not one of %s", subject.Id, formatSet(queryIds))}
+}
+
+// Explain evaluates every condition of a rule against a query, in the order Smart Cache checks them.
+func Explain(c Conditions, subject Subject) Explanation {
+	if c.IsEmpty() {
+		return Explanation{Matched: true, Conditions: []ConditionResult{
+			{Condition: NoCondition, Matched: true, Reason: "rule has no conditions and matches every query"},
+		}}
+	}
+
+	results := make([]ConditionResult, 0, 5)
+	if c.Tables != nil {
+		results = append(results, evalTables(c.Tables, subject))
+	}
+	if c.TablesAny != nil {
+		results = append(results, evalTablesAny(c.TablesAny, subject))
+	}
+	if c.TablesAll != nil {
+		results = append(results, evalTablesAll(c.TablesAll, subject))
+	}
+	if c.Regex != nil {
+		results = append(results, evalRegex(*c.Regex, subject))
+	}
+	if c.QueryIds != nil {
+		results = append(results, evalQueryIds(c.QueryIds, subject))
+	}
+
+	matched := true
+	for _, r := range results {
+		matched = matched && r.Matched
+	}
+
+	return Explanation{Matched: matched, Conditions: results}
+}
+
+// Matches reports whether a rule matches a query.
+func Matches(c Conditions, subject Subject) bool {
+	return Explain(c, subject).Matched
+}
+
+// FirstMatch returns the index of the rule that applies to a query, or -1 if none does.
+func FirstMatch(rules []Conditions, subject Subject) int {
+	for i, c := range rules {
+		if Matches(c, subject) {
+			return i
+		}
+	}
+	return -1
+}
+
+// DependsOnQuery reports whether a rule has conditions on the SQL or the ID of a query, which cannot be evaluated
+// for a table on its own.
+func (c Conditions) DependsOnQuery() bool {
+	return c.Regex != nil || c.QueryIds != nil
+}
+
+// FirstTableMatch returns the index of the first rule that applies to a query on only the given table, or -1 if
+// none does. Rules that depend on the SQL or the ID of a query are skipped since they vary between the queries on
+// the table.
+func FirstTableMatch(rules []Conditions, table string) int {
+	subject := Subject{Tables: []string{table}}
+	for i, c := range rules {
+		if !c.DependsOnQuery() && Matches(c, subject) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isSubset(a []string, b []string) bool {
+	set := toSet(b)
+	for _, v := range a {
+		if !set[v] {
+			return false
+		}
+	}
+	return true
+}
+
+func intersects(a []string, b []string) bool {
+	set := toSet(b)
+	for _, v := range a {
+		if set[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether rule a matches every query that rule b matches, so that b can never apply if a comes
+// first. It only considers what can be decided from the conditions themselves and may miss some covered rules,
+// e.g. when two different regexes match the same statements.
+func Covers(a Conditions, b Conditions) bool {
+	if a.Tables != nil {
+		if b.Tables == nil || !isSubset(a.Tables, b.Tables) || !isSubset(b.Tables, a.Tables) {
+			return false
+		}
+	}
+
+	if a.TablesAny != nil {
+		// b guarantees the query uses at least one of its tables-any, or all tables of its tables or tables-all.
+		implied := (b.TablesAny != nil && len(b.TablesAny) > 0 && isSubset(b.TablesAny, a.TablesAny)) ||
+			intersects(b.Tables, a.TablesAny) || intersects(b.TablesAll, a.TablesAny)
+		if !implied {
+			return false
+		}
+	}
+
+	if a.TablesAll != nil && len(a.TablesAll) > 0 {
+		// b guarantees the query uses all of them if its tables or tables-all include them.
+		if !isSubset(a.TablesAll, b.Tables) && !isSubset(a.TablesAll, b.TablesAll) {
+			return false
+		}
+	}
+
+	if a.Regex != nil && (b.Regex == nil || *a.Regex != *b.Regex) {
+		return false
+	}
+
+	if a.QueryIds != nil {
+		if b.QueryIds == nil || len(b.QueryIds) == 0 || !isSubset(b.QueryIds, a.QueryIds) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NeverMatches reports whether a rule cannot match any query, e.g. because it has an empty tables-any list or a
+// regex that matches no SQL.
+func NeverMatches(c Conditions) bool {
+	if c.TablesAny != nil && len(c.TablesAny) == 0 {
+		return true
+	}
+	if c.QueryIds != nil && len(c.QueryIds) == 0 {
+		return true
+	}
+	if c.Tables != nil && c.TablesAll != nil && !isSubset(c.TablesAll, c.Tables) {
+		return true
+	}
+	if c.Tables != nil && c.TablesAny != nil && !intersects(c.Tables, c.TablesAny) {
+		return true
+	}
+	if c.Regex != nil && matchesNothing(*c.Regex) {
+		return true
+	}
+	return false
+}
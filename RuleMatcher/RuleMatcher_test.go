@@ -0,0 +1,319 @@
+package RuleMatcher
+
+import (
+	"errors"
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestMatches(t *testing.T) {
+	orders := Subject{Id: "q1", Sql: "SELECT * FROM orders WHERE id = ?", Tables: []string{"orders"}}
+	join := Subject{Id: "q2", Sql: "SELECT * FROM orders o JOIN customers c ON o.cid = c.id", Tables: []string{"orders", "customers"}}
+	noTables := Subject{Id: "q3", Sql: "SELECT 1", Tables: []string{}}
+
+	tests := []struct {
+		name       string
+		conditions Conditions
+		subject    Subject
+		want       bool
+	}{
+		{"no conditions match every query", Conditions{}, orders, true},
+		{"no conditions match a query without tables", Conditions{}, noTables, true},
+
+		{"tables match the same set", Conditions{Tables: []string{"orders"}}, orders, true},
+		{"tables match regardless of order", Conditions{Tables: []string{"customers", "orders"}}, join, true},
+		{"tables reject extra query tables", Conditions{Tables: []string{"orders"}}, join, false},
+		{"tables reject missing query tables", Conditions{Tables: []string{"orders", "customers"}}, orders, false},
+		{"tables ignore duplicates", Conditions{Tables: []string{"orders", "orders"}}, orders, true},
+		{"empty tables only match queries without tables", Conditions{Tables: []string{}}, noTables, true},
+		{"empty tables reject queries with tables", Conditions{Tables: []string{}}, orders, false},
+		{"tables are case sensitive", Conditions{Tables: []string{"ORDERS"}}, orders, false},
+
+		{"tables-any match one shared table", Conditions{TablesAny: []string{"customers", "products"}}, join, true},
+		{"tables-any reject disjoint tables", Conditions{TablesAny: []string{"products"}}, join, false},
+		{"empty tables-any match nothing", Conditions{TablesAny: []string{}}, orders, false},
+
+		{"tables-all match a subset of query tables", Conditions{TablesAll: []string{"orders"}}, join, true},
+		{"tables-all match the same set", Conditions{TablesAll: []string{"orders", "customers"}}, join, true},
+		{"tables-all reject missing query tables", Conditions{TablesAll: []string{"orders", "customers"}}, orders, false},
+		{"empty tables-all match every query", Conditions{TablesAll: []string{}}, orders, true},
+
+		{"regex matches the whole SQL", Conditions{Regex: strPtr("SELECT \\* FROM orders.*")}, orders, true},
+		{"regex does not match a prefix only", Conditions{Regex: strPtr("SELECT \\* FROM orders")}, orders, false},
+		{"regex does not match a substring", Conditions{Regex: strPtr("orders")}, orders, false},
+		{"regex is case sensitive", Conditions{Regex: strPtr("select .*")}, orders, false},
+		{"regex honours the case-insensitive flag", Conditions{Regex: strPtr("(?i)select .*")}, orders, true},
+		{"regex with alternation matches in full", Conditions{Regex: strPtr("SELECT 1|SELECT 2")}, noTables, true},
+		{"unsupported regex does not match", Conditions{Regex: strPtr("(?=SELECT).*")}, orders, false},
+		{"invalid regex does not match", Conditions{Regex: strPtr("SELECT (")}, orders, false},
+
+		{"query-ids match the ID", Conditions{QueryIds: []string{"q0", "q1"}}, orders, true},
+		{"query-ids reject other IDs", Conditions{QueryIds: []string{"q2"}}, orders, false},
+
+		{"all conditions must match", Conditions{TablesAny: []string{"orders"}, Regex: strPtr(".*JOIN.*")}, join, true},
+		{"one failing condition rejects", Conditions{TablesAny: []string{"orders"}, Regex: strPtr(".*JOIN.*")}, orders, false},
+		{"query-ids and tables must both match", Conditions{Tables: []string{"orders"}, QueryIds: []string{"q2"}}, orders, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.conditions, tt.subject); got != tt.want {
+				t.Errorf("Matches() = %v, want %v, reason: %s", got, tt.want, Explain(tt.conditions, tt.subject).Reason())
+			}
+		})
+	}
+}
+
+func TestFirstMatch(t *testing.T) {
+	subject := Subject{Id: "q1", Sql: "SELECT * FROM orders", Tables: []string{"orders"}}
+
+	tests := []struct {
+		name  string
+		rules []Conditions
+		want  int
+	}{
+		{"no rules", nil, -1},
+		{"no matching rule", []Conditions{{TablesAny: []string{"products"}}}, -1},
+		{"first matching rule wins", []Conditions{{TablesAny: []string{"products"}}, {Tables: []string{"orders"}}, {}}, 1},
+		{"catch-all rule shadows later rules", []Conditions{{}, {Tables: []string{"orders"}}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FirstMatch(tt.rules, subject); got != tt.want {
+				t.Errorf("FirstMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstTableMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Conditions
+		table string
+		want  int
+	}{
+		{"tables-any", []Conditions{{TablesAny: []string{"orders", "products"}}}, "orders", 0},
+		{"tables-all with other tables does not apply", []Conditions{{TablesAll: []string{"orders", "customers"}}}, "orders", -1},
+		{"regex rules are skipped", []Conditions{{Regex: strPtr(".*")}, {Tables: []string{"orders"}}}, "orders", 1},
+		{"query-id rules are skipped", []Conditions{{QueryIds: []string{"q1"}}}, "orders", -1},
+		{"catch-all rule applies", []Conditions{{}}, "orders", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FirstTableMatch(tt.rules, tt.table); got != tt.want {
+				t.Errorf("FirstTableMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRegex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    error
+	}{
+		{"SELECT .* FROM orders.*", nil},
+		{"(?i)select .*", nil},
+		{"SELECT \\Q(?=\\E.*", nil},
+		{"[^(?=]+", nil},
+		{"(?<table>orders|customers)", nil},
+		{"\\p{L}+", nil},
+		{"(?=SELECT).*", ErrUnsupportedRegex},
+		{"(?!SELECT).*", ErrUnsupportedRegex},
+		{"(?<=FROM )orders", ErrUnsupportedRegex},
+		{"(?<!FROM )orders", ErrUnsupportedRegex},
+		{"(?>a|ab)c", ErrUnsupportedRegex},
+		{"(a)\\1", ErrUnsupportedRegex},
+		{"(?<t>a)\\k<t>", ErrUnsupportedRegex},
+		{"a*+b", ErrUnsupportedRegex},
+		{"a{2}+", ErrUnsupportedRegex},
+		{"\\p{Lower}+", ErrUnsupportedRegex},
+		{"\\p{javaLowerCase}+", ErrUnsupportedRegex},
+		{"[[:alpha:]]+", ErrUnsupportedRegex},
+		{"[a-z&&[^q]]", ErrUnsupportedRegex},
+		{"\\h+", ErrUnsupportedRegex},
+		{"SELECT.*\\Z", ErrUnsupportedRegex},
+		{"(?x)SELECT .*", ErrUnsupportedRegex},
+		{"(?U)\\w+", ErrUnsupportedRegex},
+		{"(?P<table>orders)", ErrInvalidRegex},
+		{"SELECT (", ErrInvalidRegex},
+		{"[a-z", ErrInvalidRegex},
+		{"*SELECT", ErrInvalidRegex},
+		{"SELECT\\", ErrInvalidRegex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			err := CheckRegex(tt.pattern)
+			if tt.want == nil && err != nil {
+				t.Fatalf("CheckRegex() = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("CheckRegex() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRegexNamedGroup(t *testing.T) {
+	matched, err := MatchRegex("SELECT \\* FROM (?<table>orders|customers)", "SELECT * FROM customers")
+	if err != nil || !matched {
+		t.Fatalf("MatchRegex() = %v, %v, want true, nil", matched, err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	subject := Subject{Id: "q1", Sql: "SELECT * FROM orders", Tables: []string{"orders", "customers"}}
+
+	tests := []struct {
+		name       string
+		conditions Conditions
+		want       []ConditionResult
+	}{
+		{
+			"no conditions",
+			Conditions{},
+			[]ConditionResult{{NoCondition, true, "rule has no conditions and matches every query"}},
+		},
+		{
+			"extra tables",
+			Conditions{Tables: []string{"orders"}},
+			[]ConditionResult{{TablesCondition, false, "tables [orders,customers] also include [customers]"}},
+		},
+		{
+			"conditions in evaluation order",
+			Conditions{QueryIds: []string{"q1"}, TablesAll: []string{"orders", "products"}},
+			[]ConditionResult{
+				{TablesAllCondition, false, "tables [orders,customers] do not include [products]"},
+				{QueryIdsCondition, true, "query ID q1 is one of [q1]"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Explain(tt.conditions, subject).Conditions
+			if len(got) != len(tt.want) {
+				t.Fatalf("Explain() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Explain()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	tests := []struct {
+		tables string
+		want   []string
+	}{
+		{"", []string{}},
+		{"orders", []string{"orders"}},
+		{"orders,customers", []string{"orders", "customers"}},
+		{" orders , customers ,", []string{"orders", "customers"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tables, func(t *testing.T) {
+			got := ParseTables(tt.tables)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseTables() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseTables() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Conditions
+		b    Conditions
+		want bool
+	}{
+		{"no conditions cover every rule", Conditions{}, Conditions{TablesAny: []string{"orders"}}, true},
+		{"a condition does not cover no conditions", Conditions{TablesAny: []string{"orders"}}, Conditions{}, false},
+
+		{"tables cover the same set", Conditions{Tables: []string{"orders", "customers"}}, Conditions{Tables: []string{"customers", "orders"}}, true},
+		{"tables do not cover a larger set", Conditions{Tables: []string{"orders"}}, Conditions{Tables: []string{"orders", "customers"}}, false},
+		{"tables do not cover other conditions", Conditions{Tables: []string{"orders"}}, Conditions{TablesAll: []string{"orders"}}, false},
+
+		{"tables-any cover a subset of tables-any", Conditions{TablesAny: []string{"orders", "customers"}}, Conditions{TablesAny: []string{"orders"}}, true},
+		{"tables-any do not cover a larger tables-any", Conditions{TablesAny: []string{"orders"}}, Conditions{TablesAny: []string{"orders", "customers"}}, false},
+		{"tables-any cover tables that share a table", Conditions{TablesAny: []string{"orders"}}, Conditions{Tables: []string{"orders", "customers"}}, true},
+		{"tables-any cover tables-all that share a table", Conditions{TablesAny: []string{"orders"}}, Conditions{TablesAll: []string{"orders"}}, true},
+		{"tables-any do not cover disjoint tables", Conditions{TablesAny: []string{"orders"}}, Conditions{Tables: []string{"customers"}}, false},
+
+		{"tables-all cover tables that include them", Conditions{TablesAll: []string{"orders"}}, Conditions{Tables: []string{"orders", "customers"}}, true},
+		{"tables-all cover a larger tables-all", Conditions{TablesAll: []string{"orders"}}, Conditions{TablesAll: []string{"orders", "customers"}}, true},
+		{"tables-all do not cover tables-any", Conditions{TablesAll: []string{"orders"}}, Conditions{TablesAny: []string{"orders"}}, false},
+		{"tables-all do not cover a smaller tables-all", Conditions{TablesAll: []string{"orders", "customers"}}, Conditions{TablesAll: []string{"orders"}}, false},
+
+		{"regex covers the same regex", Conditions{Regex: strPtr("SELECT .*")}, Conditions{Regex: strPtr("SELECT .*"), TablesAny: []string{"orders"}}, true},
+		{"regex does not cover another regex", Conditions{Regex: strPtr("SELECT .*")}, Conditions{Regex: strPtr("SELECT \\* FROM orders")}, false},
+		{"regex does not cover a rule without one", Conditions{Regex: strPtr("SELECT .*")}, Conditions{TablesAny: []string{"orders"}}, false},
+
+		{"query-ids cover a subset", Conditions{QueryIds: []string{"q1", "q2"}}, Conditions{QueryIds: []string{"q2"}}, true},
+		{"query-ids do not cover other IDs", Conditions{QueryIds: []string{"q1"}}, Conditions{QueryIds: []string{"q1", "q2"}}, false},
+
+		{"every condition must cover", Conditions{TablesAny: []string{"orders"}, Regex: strPtr("SELECT .*")}, Conditions{Tables: []string{"orders"}}, false},
+		{"composite rules", Conditions{TablesAny: []string{"orders"}, QueryIds: []string{"q1", "q2"}}, Conditions{Tables: []string{"orders"}, QueryIds: []string{"q1"}, Regex: strPtr("SELECT .*")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Covers(tt.a, tt.b); got != tt.want {
+				t.Errorf("Covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeverMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions Conditions
+		want       bool
+	}{
+		{"no conditions", Conditions{}, false},
+		{"empty tables-any", Conditions{TablesAny: []string{}}, true},
+		{"empty query-ids", Conditions{QueryIds: []string{}}, true},
+		{"empty tables", Conditions{Tables: []string{}}, false},
+		{"empty tables-all", Conditions{TablesAll: []string{}}, false},
+		{"tables-all outside of tables", Conditions{Tables: []string{"orders"}, TablesAll: []string{"customers"}}, true},
+		{"tables-all within tables", Conditions{Tables: []string{"orders", "customers"}, TablesAll: []string{"orders"}}, false},
+		{"tables-any disjoint from tables", Conditions{Tables: []string{"orders"}, TablesAny: []string{"customers"}}, true},
+		{"tables-any sharing a table with tables", Conditions{Tables: []string{"orders"}, TablesAny: []string{"orders", "customers"}}, false},
+
+		{"regex", Conditions{Regex: strPtr("SELECT .*")}, false},
+		{"empty character class", Conditions{Regex: strPtr("[^\\s\\S]")}, true},
+		{"concatenation with an empty character class", Conditions{Regex: strPtr("SELECT [^\\s\\S].*")}, true},
+		{"required repetition of an empty character class", Conditions{Regex: strPtr("(?:[^\\s\\S]){2}")}, true},
+		{"optional empty character class", Conditions{Regex: strPtr("SELECT [^\\s\\S]*")}, false},
+		{"alternative to an empty character class", Conditions{Regex: strPtr("SELECT 1|[^\\s\\S]")}, false},
+		{"every alternative is empty", Conditions{Regex: strPtr("[^\\s\\S]|[^\\d\\D]")}, true},
+		{"invalid regex", Conditions{Regex: strPtr("[^\\s\\S")}, false},
+		{"unsupported regex", Conditions{Regex: strPtr("(?=SELECT)[^\\s\\S]")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeverMatches(tt.conditions); got != tt.want {
+				t.Errorf("NeverMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
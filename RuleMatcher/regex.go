@@ -0,0 +1,280 @@
+package RuleMatcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	// ErrInvalidRegex is returned for patterns that Smart Cache would fail to compile as a Java regular expression.
+	ErrInvalidRegex = errors.New("invalid regex")
+	// ErrUnsupportedRegex is returned for valid Java patterns that use features Go's RE2 engine lacks or interprets
+	// differently, so the CLI cannot tell whether they match.
+	ErrUnsupportedRegex = errors.New("regex cannot be evaluated locally")
+)
+
+// RegexError describes why a rule's regex is invalid or cannot be evaluated locally.
+type RegexError struct {
+	Pattern string
+	Reason  string
+	Err     error
+}
+
+func (e *RegexError) Error() string {
+	return fmt.Sprintf("%v '%s': %s", e.Err, e.Pattern, e.Reason)
+}
+
+func (e *RegexError) Unwrap() error {
+	return e.Err
+}
+
+// posixClasses are the POSIX character classes Java supports as \p{Name}, which RE2 does not.
+var posixClasses = map[string]bool{
+	"Lower": true, "Upper": true, "ASCII": true, "Alpha": true, "Digit": true, "Alnum": true, "Punct": true,
+	"Graph": true, "Print": true, "Blank": true, "Cntrl": true, "XDigit": true, "Space": true,
+}
+
+// javaOnlyEscapes are escapes that RE2 rejects or, for \v, reads differently than Java.
+var javaOnlyEscapes = map[rune]string{
+	'h': "horizontal whitespace \\h",
+	'H': "non-horizontal whitespace \\H",
+	'v': "vertical whitespace \\v",
+	'V': "non-vertical whitespace \\V",
+	'R': "linebreak matcher \\R",
+	'X': "grapheme cluster \\X",
+	'Z': "end of input anchor \\Z",
+	'G': "end of previous match anchor \\G",
+	'e': "escape character \\e",
+	'c': "control character \\c",
+}
+
+// translateJavaRegex scans a pattern for constructs that differ between Java and RE2 and returns the equivalent RE2
+// syntax. It returns ErrInvalidRegex for syntax Java rejects and ErrUnsupportedRegex for Java features RE2 cannot
+// evaluate, both as a *RegexError.
+func translateJavaRegex(pattern string) (string, error) {
+	fail := func(sentinel error, reason string) (string, error) {
+		return "", &RegexError{Pattern: pattern, Reason: reason, Err: sentinel}
+	}
+
+	runes := []rune(pattern)
+	next := func(i int, s string) bool {
+		return strings.HasPrefix(string(runes[i:]), s)
+	}
+
+	// Older versions of RE2 only accept the (?P<name>...) form of named groups.
+	namedGroups := make(map[int]bool)
+	classDepth := 0
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		if c == '\\' {
+			if i+1 >= len(runes) {
+				return fail(ErrInvalidRegex, "trailing backslash")
+			}
+			e := runes[i+1]
+			switch {
+			case e == 'Q':
+				// Everything up to \E is literal.
+				j := i + 2
+				for j+1 < len(runes) && !(runes[j] == '\\' && runes[j+1] == 'E') {
+					j++
+				}
+				if j+1 >= len(runes) {
+					i = len(runes)
+					continue
+				}
+				i = j + 1
+				continue
+			case e >= '1' && e <= '9' && classDepth == 0:
+				return fail(ErrUnsupportedRegex, "back-references are not supported by RE2")
+			case e == 'k' && next(i+2, "<"):
+				return fail(ErrUnsupportedRegex, "named back-references are not supported by RE2")
+			case e == 'p' || e == 'P':
+				if next(i+2, "{") {
+					end := i + 3
+					for end < len(runes) && runes[end] != '}' {
+						end++
+					}
+					if end < len(runes) {
+						name := string(runes[i+3 : end])
+						if strings.HasPrefix(name, "java") || posixClasses[name] ||
+							strings.HasPrefix(name, "Is") || (strings.HasPrefix(name, "In") && name != "Inherited") || strings.Contains(name, "=") {
+							return fail(ErrUnsupportedRegex, fmt.Sprintf("character class \\%c{%s} is not supported by RE2", e, name))
+						}
+						// Skip the name so that the closing brace is not taken for a quantifier.
+						i = end
+						continue
+					}
+				}
+			default:
+				if reason, ok := javaOnlyEscapes[e]; ok {
+					return fail(ErrUnsupportedRegex, reason+" is not supported by RE2")
+				}
+			}
+			i++
+			continue
+		}
+
+		if classDepth > 0 {
+			switch c {
+			case '[':
+				if next(i+1, ":") {
+					return fail(ErrUnsupportedRegex, "POSIX bracket expressions such as [[:alpha:]] are nested classes in Java, use \\p{Alpha}")
+				}
+				return fail(ErrUnsupportedRegex, "nested character classes are not supported by RE2")
+			case '&':
+				if next(i+1, "&") {
+					return fail(ErrUnsupportedRegex, "character class intersections are not supported by RE2")
+				}
+			case ']':
+				classDepth--
+			}
+			continue
+		}
+
+		switch c {
+		case '[':
+			classDepth++
+			// A leading ^ negates the class rather than ending it.
+			if next(i+1, "^") {
+				i++
+			}
+		case '(':
+			if !next(i+1, "?") {
+				continue
+			}
+			switch {
+			case next(i+2, "=") || next(i+2, "!"):
+				return fail(ErrUnsupportedRegex, "lookahead is not supported by RE2")
+			case next(i+2, "<=") || next(i+2, "<!"):
+				return fail(ErrUnsupportedRegex, "lookbehind is not supported by RE2")
+			case next(i+2, ">"):
+				return fail(ErrUnsupportedRegex, "atomic groups are not supported by RE2")
+			case next(i+2, "P<"):
+				return fail(ErrInvalidRegex, "(?P<name>...) is Go syntax, Java names groups with (?<name>...)")
+			case next(i+2, "<"):
+				namedGroups[i] = true
+			default:
+				for j := i + 2; j < len(runes) && (unicode.IsLetter(runes[j]) || runes[j] == '-'); j++ {
+					switch runes[j] {
+					case 'U':
+						return fail(ErrUnsupportedRegex, "flag U makes quantifiers ungreedy in RE2 but enables Unicode classes in Java")
+					case 'd', 'u', 'x':
+						return fail(ErrUnsupportedRegex, fmt.Sprintf("flag %c is not supported by RE2", runes[j]))
+					}
+				}
+			}
+			// Skip the ? so that it is not taken for a quantifier.
+			i++
+		case '*', '+', '?', '}':
+			if next(i+1, "+") {
+				return fail(ErrUnsupportedRegex, "possessive quantifiers are not supported by RE2")
+			}
+		}
+	}
+
+	if classDepth > 0 {
+		return fail(ErrInvalidRegex, "unclosed character class")
+	}
+
+	translated := strings.Builder{}
+	for i := 0; i < len(runes); i++ {
+		if namedGroups[i] {
+			translated.WriteString("(?P")
+			i++
+			continue
+		}
+		translated.WriteRune(runes[i])
+	}
+
+	return translated.String(), nil
+}
+
+type compiledRegex struct {
+	re  *regexp.Regexp
+	err error
+}
+
+var regexCache sync.Map
+
+// compile translates a Java pattern into an RE2 expression that, like Java's Matcher.matches, only matches the
+// whole input.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if c, ok := regexCache.Load(pattern); ok {
+		return c.(compiledRegex).re, c.(compiledRegex).err
+	}
+
+	var re *regexp.Regexp
+	translated, err := translateJavaRegex(pattern)
+	if err == nil {
+		re, err = regexp.Compile(`^(?:` + translated + `)$`)
+		if err != nil {
+			err = &RegexError{Pattern: pattern, Reason: err.Error(), Err: ErrInvalidRegex}
+		}
+	}
+
+	regexCache.Store(pattern, compiledRegex{re: re, err: err})
+	return re, err
+}
+
+// CheckRegex reports whether Smart Cache can compile the pattern and the CLI can evaluate it. Errors match
+// ErrInvalidRegex or ErrUnsupportedRegex.
+func CheckRegex(pattern string) error {
+	_, err := compile(pattern)
+	return err
+}
+
+// MatchRegex reports whether the SQL matches the pattern in full, as Smart Cache's Matcher.matches does.
+func MatchRegex(pattern string, sql string) (bool, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(sql), nil
+}
+
+// matchesNothing reports whether a pattern the CLI can evaluate matches no input at all, e.g. an empty character
+// class such as [^\s\S]. Patterns it cannot evaluate are reported by CheckRegex instead.
+func matchesNothing(pattern string) bool {
+	translated, err := translateJavaRegex(pattern)
+	if err != nil {
+		return false
+	}
+	re, err := syntax.Parse(translated, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	return isNoMatch(re.Simplify())
+}
+
+func isNoMatch(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return true
+	case syntax.OpCharClass:
+		return len(re.Rune) == 0
+	case syntax.OpCapture, syntax.OpPlus:
+		return isNoMatch(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min > 0 && isNoMatch(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if isNoMatch(sub) {
+				return true
+			}
+		}
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !isNoMatch(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -3,14 +3,14 @@ package RuleTtlView
 import (
 	"fmt"
 	"smart-cache-cli/RedisCommon"
-	"smart-cache-cli/util"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// TableTtlMsg sets the TTL of the queries using a table, a TTL of RedisCommon.NoCache disables caching for them.
 type TableTtlMsg struct {
-	Ttl string
+	Ttl RedisCommon.Ttl
 }
 
 type Model struct {
@@ -32,12 +32,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlC.String():
 			*m.parentModel, _ = (*m.parentModel).Update(msg)
 			return *m.parentModel, tea.Quit
+		case tea.KeyCtrlD.String():
+			*m.parentModel, cmd = (*m.parentModel).Update(TableTtlMsg{Ttl: RedisCommon.NoCache})
+			return *m.parentModel, cmd
 		case tea.KeyEnter.String():
-			err := util.ValidateTimeout(m.textInput.Value())
+			ttl, err := RedisCommon.ParseRuleTtl(m.textInput.Value(), false)
 			if err != nil {
 				m.err = "\n" + err.Error()
 			} else {
-				*m.parentModel, cmd = (*m.parentModel).Update(TableTtlMsg{Ttl: m.textInput.Value()})
+				*m.parentModel, cmd = (*m.parentModel).Update(TableTtlMsg{Ttl: ttl})
 				return *m.parentModel, cmd
 			}
 		}
@@ -53,7 +56,7 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) View() string {
-	return fmt.Sprintf("%s\n\nPress [ESC] to return to the previous screen.\nEnter TTL in the form of a duration (e.g. 300s, 5m, 1h):\n%s%s", m.table.Formatted(), m.textInput.View(), m.err)
+	return fmt.Sprintf("%s\n\nPress [ESC] to return to the previous screen.\nPress [CTRL+D] to disable caching for queries using this table.\nEnter TTL in the form of a duration (e.g. 300s, 5m, 1h):\n%s%s", m.table.Formatted(), m.textInput.View(), m.err)
 }
 
 func New(table *RedisCommon.Table, parentModel tea.Model) Model {
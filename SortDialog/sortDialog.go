@@ -90,8 +90,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						Choice:    m.choice,
 						Direction: m.direction,
 					}
-					m.parentModel, _ = m.parentModel.Update(sm)
-					return m.parentModel, nil
+					var cmd tea.Cmd
+					m.parentModel, cmd = m.parentModel.Update(sm)
+					return m.parentModel, cmd
 				}
 
 			} else {
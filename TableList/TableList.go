@@ -5,12 +5,12 @@ import (
 	"smart-cache-cli/RedisCommon"
 	"smart-cache-cli/RuleTtlView"
 	"smart-cache-cli/SortDialog"
+	"smart-cache-cli/util"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/evertras/bubble-table/table"
-	"github.com/redis/go-redis/v9"
 )
 
 var (
@@ -36,13 +36,13 @@ var (
 )
 
 type Model struct {
-	parentModel     tea.Model
-	table           table.Model
-	tables          []RedisCommon.Table
-	rdb             *redis.Client
-	sortColumn      string
-	sortDirection   SortDialog.Direction
-	applicationName string
+	parentModel   tea.Model
+	table         table.Model
+	tables        []RedisCommon.Table
+	store         RedisCommon.SmartCacheStore
+	sortColumn    string
+	sortDirection SortDialog.Direction
+	err           error
 }
 
 func (m Model) Selection() *RedisCommon.Table {
@@ -61,28 +61,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlC.String():
 			return m.parentModel, tea.Quit
 		case tea.KeyEnter.String():
+			if len(m.tables) == 0 {
+				return m, nil
+			}
 			return RuleTtlView.New(m.Selection(), m), cmd
 		case "b", tea.KeyEsc.String():
 			m.parentModel, _ = m.parentModel.Update(ConfirmationDialog.ConfirmationMessage{ConfirmedUpdate: true})
 			return m.parentModel, nil
 		case "s":
-			return SortDialog.New([]string{"Access Frequency", "Query Time"}, m), nil
+			return SortDialog.New([]string{"Access Frequency", "Query Time", "TTL"}, m), nil
 
 		}
 	case RuleTtlView.TableTtlMsg:
 		rule := RedisCommon.Rule{
-			Ttl:       msg.Ttl,
+			Ttl:       msg.Ttl.String(),
 			TablesAny: []string{m.Selection().Name},
 		}
-		RedisCommon.CommitNewRules(m.rdb, []RedisCommon.Rule{rule}, m.applicationName)
+		_, err := m.store.CommitNewRules([]RedisCommon.Rule{rule}, RedisCommon.TopPosition)
+		if err != nil {
+			m.err = err
+			return m, cmd
+		}
 		ResetModel(&m)
 		return m, cmd
 	case SortDialog.SortMessage:
 		columns := RedisCommon.GetColumnsOfTable(msg.Choice, msg.Direction)
 		if msg.Direction == SortDialog.Descending {
-			m.table = m.table.WithColumns(columns).SortByDesc(msg.Choice)
+			m.table = m.table.WithColumns(columns).SortByDesc(RedisCommon.SortKey(msg.Choice))
 		} else {
-			m.table = m.table.WithColumns(columns).SortByAsc(msg.Choice)
+			m.table = m.table.WithColumns(columns).SortByAsc(RedisCommon.SortKey(msg.Choice))
 		}
 	}
 	m.table, cmd = m.table.Update(msg)
@@ -95,12 +102,15 @@ func (m Model) View() string {
 	body.WriteString("Press 'b' to go back\n")
 	body.WriteString("Press 's' to change sorting\n\n")
 	body.WriteString(m.table.View())
+	body.WriteString(util.StatusLine(m.err))
 
 	return body.String()
 }
 
 func ResetModel(m *Model) {
-	tables := RedisCommon.GetTables(m.rdb, m.applicationName)
+	tables, err := m.store.GetTables()
+	m.err = err
+	m.tables = tables
 
 	rows := make([]table.Row, len(tables))
 	for i, t := range tables {
@@ -110,8 +120,8 @@ func ResetModel(m *Model) {
 	m.table = m.table.WithRows(rows)
 }
 
-func New(parentModel tea.Model, rdb *redis.Client, applicationName string) Model {
-	tables := RedisCommon.GetTables(rdb, applicationName)
+func New(parentModel tea.Model, store RedisCommon.SmartCacheStore) Model {
+	tables, err := store.GetTables()
 
 	rows := make([]table.Row, len(tables))
 	for i, t := range tables {
@@ -127,11 +137,11 @@ func New(parentModel tea.Model, rdb *redis.Client, applicationName string) Model
 			Border(customBorder).
 			WithPageSize(5).
 			SortByDesc("Query Time").WithTargetWidth(200),
-		parentModel:     parentModel,
-		rdb:             rdb,
-		applicationName: applicationName,
-		sortColumn:      "Query Time",
-		sortDirection:   SortDialog.Descending,
+		parentModel:   parentModel,
+		store:         store,
+		sortColumn:    "Query Time",
+		sortDirection: SortDialog.Descending,
+		err:           err,
 	}
 
 	return model
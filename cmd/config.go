@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "SMARTCACHE_"
+
+var (
+	ProfileName string
+	ConfigPath  string
+)
+
+// ProfileTls holds the TLS settings of a connection profile.
+type ProfileTls struct {
+	Enabled    *bool  `yaml:"enabled"`
+	CaCert     string `yaml:"cacert"`
+	Cert       string `yaml:"cert"`
+	Key        string `yaml:"key"`
+	ServerName string `yaml:"sni"`
+	Insecure   *bool  `yaml:"insecure"`
+}
+
+// Profile is a named set of connection settings in the config file.
+type Profile struct {
+	Uri             string     `yaml:"uri"`
+	Host            string     `yaml:"host"`
+	Port            string     `yaml:"port"`
+	Db              *int       `yaml:"db"`
+	User            string     `yaml:"user"`
+	Password        string     `yaml:"password"`
+	PasswordCommand string     `yaml:"password-command"`
+	Application     string     `yaml:"application"`
+	SentinelMaster  string     `yaml:"sentinel-master"`
+	Cluster         *bool      `yaml:"cluster"`
+	SeedAddrs       []string   `yaml:"seed-addrs"`
+	MinTtl          string     `yaml:"min-ttl"`
+	MaxTtl          string     `yaml:"max-ttl"`
+	Tls             ProfileTls `yaml:"tls"`
+}
+
+// Config is the layout of the config file, e.g. ~/.config/smart-cache-cli/config.yaml:
+//
+//	default-profile: local
+//	profiles:
+//	  local:
+//	    host: localhost
+//	    port: "6379"
+//	  production:
+//	    host: redis-12000.example.com
+//	    port: "12000"
+//	    password-command: pass show redis/production
+//	    application: orders
+//	    min-ttl: 10s
+//	    max-ttl: 7d
+//	    tls:
+//	      cacert: /etc/ssl/redis-ca.pem
+type Config struct {
+	DefaultProfile string             `yaml:"default-profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "smart-cache-cli", "config.yaml")
+}
+
+func loadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config file '%s': %w", path, err)
+	}
+
+	return &config, nil
+}
+
+func setIfNotEmpty(settings map[string]string, key string, value string) {
+	if value != "" {
+		settings[key] = value
+	}
+}
+
+func setIfPresent(settings map[string]string, key string, value *bool) {
+	if value != nil {
+		settings[key] = strconv.FormatBool(*value)
+	}
+}
+
+// settings maps the profile onto the names of the flags it provides values for.
+func (p Profile) settings() map[string]string {
+	settings := make(map[string]string)
+	setIfNotEmpty(settings, "uri", p.Uri)
+	setIfNotEmpty(settings, "host", p.Host)
+	setIfNotEmpty(settings, "port", p.Port)
+	setIfNotEmpty(settings, "user", p.User)
+	setIfNotEmpty(settings, "password", p.Password)
+	setIfNotEmpty(settings, "password-command", p.PasswordCommand)
+	setIfNotEmpty(settings, "application", p.Application)
+	setIfNotEmpty(settings, "sentinel-master", p.SentinelMaster)
+	setIfNotEmpty(settings, "seed-addrs", strings.Join(p.SeedAddrs, ","))
+	setIfNotEmpty(settings, "min-ttl", p.MinTtl)
+	setIfNotEmpty(settings, "max-ttl", p.MaxTtl)
+	setIfNotEmpty(settings, "cacert", p.Tls.CaCert)
+	setIfNotEmpty(settings, "cert", p.Tls.Cert)
+	setIfNotEmpty(settings, "key", p.Tls.Key)
+	setIfNotEmpty(settings, "sni", p.Tls.ServerName)
+	setIfPresent(settings, "tls", p.Tls.Enabled)
+	setIfPresent(settings, "insecure", p.Tls.Insecure)
+	setIfPresent(settings, "cluster", p.Cluster)
+	if p.Db != nil {
+		settings["db"] = strconv.Itoa(*p.Db)
+	}
+	return settings
+}
+
+// envName is the environment variable that overrides a flag, e.g. SMARTCACHE_SENTINEL_MASTER for --sentinel-master.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// selectProfile finds the profile requested with --profile (or SMARTCACHE_PROFILE), falling back to the
+// config file's default profile. A missing config file is only an error when a profile or config path was
+// explicitly requested.
+func selectProfile(flags *pflag.FlagSet) (*Profile, error) {
+	if !flags.Changed("profile") {
+		if env, ok := os.LookupEnv(envName("profile")); ok {
+			ProfileName = env
+		}
+	}
+
+	explicitPath := flags.Changed("config")
+	if !explicitPath {
+		if env, ok := os.LookupEnv(envName("config")); ok {
+			ConfigPath = env
+			explicitPath = true
+		}
+	}
+
+	if ConfigPath == "" {
+		ConfigPath = defaultConfigPath()
+	}
+
+	config, err := loadConfig(ConfigPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicitPath && ProfileName == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	name := ProfileName
+	if name == "" {
+		name = config.DefaultProfile
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found in config file '%s'", name, ConfigPath)
+	}
+
+	return &profile, nil
+}
+
+// applySettings resolves every persistent flag that was not given explicitly, first from its SMARTCACHE_
+// environment variable and then from the selected profile. Explicit flags win over both.
+func applySettings(cmd *cobra.Command, args []string) error {
+	// flags have been parsed by now, so any further errors are not usage errors
+	cmd.SilenceUsage = true
+	flags := cmd.Flags()
+
+	profile, err := selectProfile(flags)
+	if err != nil {
+		return err
+	}
+
+	profileSettings := make(map[string]string)
+	if profile != nil {
+		profileSettings = profile.settings()
+	}
+
+	var setErr error
+	cmd.Root().PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if setErr != nil || flag.Changed || flag.Name == "profile" || flag.Name == "config" {
+			return
+		}
+
+		value, ok := os.LookupEnv(envName(flag.Name))
+		if !ok {
+			value, ok = profileSettings[flag.Name]
+		}
+
+		if ok {
+			if sv, isSlice := flag.Value.(pflag.SliceValue); isSlice {
+				setErr = sv.Replace(strings.Split(value, ","))
+			} else {
+				setErr = flag.Value.Set(value)
+			}
+			if setErr != nil {
+				setErr = fmt.Errorf("invalid value '%s' for %s: %w", value, flag.Name, setErr)
+			}
+		}
+	})
+	if setErr != nil {
+		return setErr
+	}
+
+	if err := applyTtlPolicy(); err != nil {
+		return err
+	}
+
+	return resolvePassword(flags, profileSettings)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&ProfileName, "profile", "", "Named connection profile from the config file")
+	rootCmd.PersistentFlags().StringVar(&ConfigPath, "config", "", "Path to the config file (default is $XDG_CONFIG_HOME/smart-cache-cli/config.yaml)")
+	rootCmd.PersistentPreRunE = applySettings
+}
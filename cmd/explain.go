@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"smart-cache-cli/RedisCommon"
+
+	"github.com/spf13/cobra"
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain <query-id>",
+	Short: "Explain which caching rule applies to a query and why",
+	Long: `Evaluates every caching rule against a query in order of precedence, showing for each rule whether
+it matches and why, and which rule determines the query's TTL.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+
+		queries, err := store.GetQueries()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		var query *RedisCommon.Query
+		for _, q := range queries {
+			if q.Id == args[0] {
+				query = q
+			}
+		}
+
+		if query == nil {
+			fmt.Printf("No query with ID '%s' has been seen by Smart Cache.\n", args[0])
+			os.Exit(1)
+		}
+
+		rules, err := store.GetRules()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Query %s on tables [%s]:\n%s\n\n", query.Id, query.Table, query.Sql)
+		fmt.Print(RedisCommon.ExplainQuery(query, rules).Formatted())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
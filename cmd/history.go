@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"smart-cache-cli/RedisCommon"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past versions of the caching rules",
+	Long: `Lists the entries of the application's config stream, newest first. Every rule change made by
+the CLI is a new entry, so this is the full history of the caching rules.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+
+		// Load one more entry than is shown so that the oldest one shown can be compared to its predecessor.
+		fetch := historyCount
+		if fetch > 0 {
+			fetch++
+		}
+
+		entries, err := store.GetRuleHistory(fetch)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		shown := len(entries)
+		if historyCount > 0 && int64(shown) > historyCount {
+			shown = int(historyCount)
+		}
+
+		fmt.Println(RedisCommon.GetHistoryHeader(20))
+		for i := 0; i < shown; i++ {
+			fmt.Println(entries[i].GetRow(20, RedisCommon.Previous(entries, i)))
+		}
+	},
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <from-entry-id> [<to-entry-id>]",
+	Short: "Show the rule changes between two config entries",
+	Long: `Shows a rule-level diff between two entries of the config stream. When the second entry is
+omitted the current rules are used.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+
+		from, err := store.GetRuleSetAt(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		var to RedisCommon.RuleSet
+		if len(args) > 1 {
+			to, err = store.GetRuleSetAt(args[1])
+		} else {
+			to, err = store.GetRuleSet()
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("--- %s\n+++ %s\n", from.Id, to.Id)
+		fmt.Print(RedisCommon.FormatRuleDiff(RedisCommon.DiffRules(from.Rules, to.Rules)))
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <entry-id>",
+	Short: "Restore the caching rules of a past config entry",
+	Long: `Re-publishes the rules of a past config entry as the current rules. The entry itself is left
+in place, so a rollback can be rolled back in turn.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+
+		current, err := store.GetRuleSet()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		target, err := store.GetRuleSetAt(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		changes := RedisCommon.DiffRules(current.Rules, target.Rules)
+		if !RedisCommon.HasChanges(changes) {
+			fmt.Printf("The current rules already match entry %s.\n", target.Id)
+			return
+		}
+
+		fmt.Printf("Rolling back to entry %s changes the current rules as follows:\n", target.Id)
+		fmt.Print(RedisCommon.FormatRuleDiff(changes))
+
+		if !rollbackConfirmed && !askYesNo("Do you want to continue? (y/N) ") {
+			return
+		}
+
+		id, err := store.RollbackRules(current.Id, target.Id)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Rolled back to entry %s as new entry %s.\n", target.Id, id)
+	},
+}
+
+// askYesNo prompts on stderr, so that it stays out of the output of the command, and reads the answer from stdin.
+// Anything other than y or yes counts as no. It exits with exitNotInteractive if stdin is not a terminal.
+func askYesNo(prompt string) bool {
+	mustBeInteractive()
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// mustBeInteractive exits with exitNotInteractive if stdin is not a terminal, as a confirmation could not be answered.
+func mustBeInteractive() {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("Unable to ask for confirmation as stdin is not a terminal, provide --confirm to continue without it.")
+		os.Exit(exitNotInteractive)
+	}
+}
+
+var (
+	historyCount      int64
+	rollbackConfirmed bool
+)
+
+func init() {
+	historyCmd.Flags().Int64VarP(&historyCount, "count", "c", 20, "The number of entries to list, 0 lists the whole history.")
+	rollbackCmd.Flags().BoolVarP(&rollbackConfirmed, "confirm", "y", false, "provide this flag if you don't want to be asked for confirmation before rolling back.")
+
+	historyCmd.AddCommand(historyDiffCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(rollbackCmd)
+}
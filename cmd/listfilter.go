@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"smart-cache-cli/RedisCommon"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type sortDir string
+
+const (
+	desc sortDir = "desc"
+	asc          = "asc"
+)
+
+var (
+	filterTable     string
+	filterSqlRegex  string
+	filterCached    bool
+	filterUncached  bool
+	filterMinCount  uint64
+	filterMinMeanMs float64
+	filterRuleIndex int
+	filterLimit     int
+)
+
+// addListFlags adds the sort and filter flags of a list command, what is one of "queries" and "tables".
+func addListFlags(cmd *cobra.Command, what string, sortKeys []string) {
+	cmd.Flags().StringVarP(&sortby, "sortby", "b", "queryTime", fmt.Sprintf("The field to sort the %s by. Valid options are %s.", what, strings.Join(sortKeys, ", ")))
+	cmd.Flags().StringVarP(&sortDirection, "sortDirection", "d", "DESC", "the direction to sort. Valid options are 'ASC' and 'DESC'.")
+
+	if what == "queries" {
+		cmd.Flags().StringVar(&filterTable, "table", "", "Only list the queries that use this table.")
+		cmd.Flags().StringVar(&filterSqlRegex, "sql-regex", "", "Only list the queries whose SQL matches this regular expression anywhere.")
+	} else {
+		cmd.Flags().StringVar(&filterTable, "table", "", "Only list the table of this name.")
+	}
+	cmd.Flags().BoolVar(&filterCached, "cached", false, fmt.Sprintf("Only list the %s that are cached.", what))
+	cmd.Flags().BoolVar(&filterUncached, "uncached", false, fmt.Sprintf("Only list the %s that are not cached.", what))
+	cmd.Flags().Uint64Var(&filterMinCount, "min-count", 0, fmt.Sprintf("Only list the %s accessed at least this often.", what))
+	cmd.Flags().Float64Var(&filterMinMeanMs, "min-mean-ms", 0, fmt.Sprintf("Only list the %s with a mean query time of at least this many milliseconds.", what))
+	cmd.Flags().IntVar(&filterRuleIndex, "rule-index", 0, fmt.Sprintf("Only list the %s that the rule with this precedence, starting at 0, applies to.", what))
+	cmd.Flags().IntVar(&filterLimit, "limit", 0, fmt.Sprintf("List at most this many %s, after sorting.", what))
+	cmd.MarkFlagsMutuallyExclusive("cached", "uncached")
+}
+
+// parseListFlags checks the sort and filter flags against the sort keys of the list command, returning the filter they
+// describe and whether to sort descending.
+func parseListFlags(cmd *cobra.Command, sortKeys []string) (RedisCommon.ListFilter, bool, error) {
+	filter := RedisCommon.ListFilter{
+		Table:     filterTable,
+		Cached:    filterCached,
+		Uncached:  filterUncached,
+		MinCount:  filterMinCount,
+		MinMeanMs: filterMinMeanMs,
+		Limit:     filterLimit,
+	}
+
+	if err := RedisCommon.CheckSortKey(sortby, sortKeys); err != nil {
+		return filter, false, err
+	}
+
+	sdLower := strings.ToLower(sortDirection)
+	if sdLower != string(desc) && sdLower != asc {
+		return filter, false, fmt.Errorf("%s is not a valid sort order. Valid orders are 'ASC' and 'DESC'.", sortDirection)
+	}
+
+	if filterSqlRegex != "" {
+		re, err := regexp.Compile(filterSqlRegex)
+		if err != nil {
+			return filter, false, fmt.Errorf("invalid --sql-regex: %w", err)
+		}
+		filter.SqlRegex = re
+	}
+
+	if cmd.Flags().Changed("rule-index") {
+		if filterRuleIndex < 0 {
+			return filter, false, fmt.Errorf("--rule-index must not be negative")
+		}
+		filter.RuleIndex = &filterRuleIndex
+	}
+
+	if filterLimit < 0 {
+		return filter, false, fmt.Errorf("--limit must not be negative")
+	}
+
+	return filter, sdLower == string(desc), nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"smart-cache-cli/RedisCommon"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseListFlagsSortKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		sortKeys []string
+		wantErr  bool
+	}{
+		{"default", nil, RedisCommon.QuerySortKeys, false},
+		{"query key in another case", []string{"--sortby", "AccessFrequency"}, RedisCommon.QuerySortKeys, false},
+		{"table key", []string{"-b", "name"}, RedisCommon.TableSortKeys, false},
+		{"query key for tables", []string{"--sortby", "id"}, RedisCommon.TableSortKeys, true},
+		{"unknown key", []string{"--sortby", "banana"}, RedisCommon.QuerySortKeys, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addListFlags(cmd, "queries", tt.sortKeys)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+
+			_, _, err := parseListFlags(cmd, tt.sortKeys)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseListFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -7,10 +7,6 @@ import (
 	"fmt"
 	"os"
 	"smart-cache-cli/RedisCommon"
-	"sort"
-	"strings"
-
-	"github.com/redis/go-redis/v9"
 
 	"github.com/spf13/cobra"
 )
@@ -21,55 +17,57 @@ var listtablesCmd = &cobra.Command{
 	Short: "List the tables being profiled by Redis Smart Cache",
 	Long:  `List the tables being profiled by Redis Smart Cache`,
 	Run: func(cmd *cobra.Command, args []string) {
-		rdb := redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%s", HostName, Port),
-			Password: Password,
-			Username: User,
-			DB:       0,
-			Protocol: 2,
-		})
+		tmpl, err := parseOutputFlags(cmd)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		filter, descending, err := parseListFlags(cmd, RedisCommon.TableSortKeys)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-		tables := RedisCommon.GetTables(rdb, ApplicationName)
+		store, _ := mustOpenStore(cmd)
 
-		sbLower := strings.ToLower(sortby)
-		sdLower := strings.ToLower(sortDirection)
+		tables, err := store.GetTables()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-		if sdLower != string(desc) && sdLower != asc {
-			fmt.Println(fmt.Sprintf("%s is not a valid sort order. Valid orders are 'ASC' and 'DESC'.", sortDirection))
+		tables = filter.FilterTables(tables)
+		err = RedisCommon.SortTables(tables, sortby, descending)
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
+		tables = filter.LimitTables(tables)
 
-		switch sbLower {
-		case string(queryTime):
-			sort.Slice(tables, func(i int, j int) bool {
-				if sdLower == string(desc) {
-					return tables[i].QueryTime > tables[j].QueryTime
-				} else {
-					return tables[i].QueryTime < tables[j].QueryTime
-				}
-			})
-		case accessFrequency:
-			sort.Slice(tables, func(i int, j int) bool {
-				if sdLower == string(desc) {
-					return tables[i].AccessFrequency > tables[j].AccessFrequency
-				} else {
-					return tables[i].AccessFrequency < tables[j].AccessFrequency
-				}
-			})
+		if tmpl == nil && outputFormat == tableOutput {
+			fmt.Println(RedisCommon.GetTablesTableHeader(20))
+			for _, t := range tables {
+				fmt.Println(t.GetRow(20))
+			}
+			return
 		}
 
-		fmt.Println(RedisCommon.GetTablesTableHeader(20))
-		for _, t := range tables {
-			fmt.Println(t.GetRow(20))
+		records := make([]RedisCommon.TableRecord, len(tables))
+		for i, t := range tables {
+			records[i] = t.Record()
+		}
+		err = writeRecords(os.Stdout, outputFormat, tmpl, records)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 	},
 }
 
 func init() {
-	listtablesCmd.Flags().StringVarP(&sortby, "sortby", "b", "queryTime", "The field in the"+
-		" tables table to use to sort. Valid options include 'queryTime', 'accessFrequency'")
-	listtablesCmd.Flags().StringVarP(&sortDirection, "sortDirection", "d", "DESC", "the direction to "+
-		"sort. Valid options are 'ASC' and 'DESC'.")
+	addListFlags(listtablesCmd, "tables", RedisCommon.TableSortKeys)
+	addOutputFlags(listtablesCmd)
 
 	rootCmd.AddCommand(listtablesCmd)
 }
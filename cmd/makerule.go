@@ -4,13 +4,14 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"smart-cache-cli/ConfirmationDialog"
 	"smart-cache-cli/RedisCommon"
-	"strings"
+	"smart-cache-cli/RuleMatcher"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/redis/go-redis/v9"
 
 	"github.com/spf13/cobra"
 )
@@ -19,66 +20,130 @@ import (
 var makeruleCmd = &cobra.Command{
 	Use:   "makerule",
 	Short: "Create a caching rule",
-	Long:  `Creates a caching rule`,
+	Long: `Creates a caching rule from at least one condition and either a TTL or --no-cache. The rule is inserted ahead
+of the current rules unless --position says otherwise.
+
+Exits with 2 if the rule or position is invalid and with 3 if the rule needs confirming but stdin is not a terminal.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("makerule called")
-		rdb := redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%s", HostName, Port),
-			Password: Password,
-			Username: User,
-			DB:       0,
-			Protocol: 2,
-		})
+		if ttl == "" && !noCache {
+			fmt.Println("Either --ttl or --no-cache is required.")
+			os.Exit(exitInvalidRule)
+		}
 
-		rule := RedisCommon.Rule{Ttl: ttl}
-		numConditions := 0
-		if tablesExact != "" {
-			rule.Tables = strings.Split(tablesExact, ",")
-			numConditions++
+		position, err := RedisCommon.ParseRulePosition(makerulePosition)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(exitInvalidRule)
 		}
 
-		if tablesAny != "" {
-			rule.TablesAny = strings.Split(tablesAny, ",")
-			numConditions++
+		base := RedisCommon.Rule{}
+		if makeruleFromQuery != "" {
+			base.QueryIds = []string{makeruleFromQuery}
+		}
+		if makeruleFromTable != "" {
+			base.TablesAny = []string{makeruleFromTable}
 		}
 
-		if tablesAll != "" {
-			rule.TablesAll = strings.Split(tablesAll, ",")
-			numConditions++
+		rule, err := applyRuleFlags(cmd, base)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(exitInvalidRule)
 		}
 
-		if regex != "" {
-			rule.Regex = &regex
-			numConditions++
+		if !confirmed && !makeruleDryRun {
+			mustBeInteractive()
 		}
 
-		if queryIds != "" {
-			rule.QueryIds = strings.Split(queryIds, ",")
-			numConditions++
+		store, _ := mustOpenStore(cmd)
+		mustCheckRuleTargets(store, position)
+
+		if makeruleDryRun {
+			impact, err := RedisCommon.PreviewNewRules(store, []RedisCommon.Rule{rule}, position)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if position != RedisCommon.TopPosition {
+				fmt.Printf("Inserted at position: %s\n", position)
+			}
+			fmt.Print(rule.Formatted())
+			fmt.Print(impact.Formatted())
+			return
 		}
 
 		if !confirmed {
-			m := ConfirmationDialog.New(nil, map[string]RedisCommon.Rule{rule.Ttl: rule})
+			m := ConfirmationDialog.NewAt(nil, map[string]RedisCommon.Rule{rule.Ttl: rule}, store, position)
 			p := tea.NewProgram(m)
 			res, err := p.Run()
 			if err != nil {
-				panic(err)
+				fmt.Println(err)
+				os.Exit(1)
 			}
 
 			confirmed = res.(ConfirmationDialog.Model).Confirmed
 		}
 
-		if confirmed {
-			_, err := RedisCommon.CommitNewRules(rdb, []RedisCommon.Rule{rule}, ApplicationName)
-			if err != nil {
-				panic(err)
-			}
-			fmt.Println("Successfully created caching rule.")
+		if !confirmed {
+			fmt.Println("The caching rule was not created.")
+			return
 		}
 
+		_, err = store.CommitNewRules([]RedisCommon.Rule{rule}, position)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Successfully created caching rule.")
 	},
 }
 
+// mustCheckRuleTargets checks that the query of --from-query and the table of --from-table exist, and that the
+// position is among the current rules.
+func mustCheckRuleTargets(store RedisCommon.SmartCacheStore, position RedisCommon.RulePosition) {
+	if makeruleFromQuery != "" {
+		queries, err := store.GetQueries()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		found := false
+		for _, q := range queries {
+			found = found || q.Id == makeruleFromQuery
+		}
+		if !found {
+			fmt.Printf("Query %s does not exist.\n", makeruleFromQuery)
+			os.Exit(exitInvalidRule)
+		}
+	}
+
+	if makeruleFromTable != "" {
+		tables, err := store.GetTables()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		found := false
+		for _, t := range tables {
+			found = found || t.Name == makeruleFromTable
+		}
+		if !found {
+			fmt.Printf("Table %s does not exist.\n", makeruleFromTable)
+			os.Exit(exitInvalidRule)
+		}
+	}
+
+	rules, err := store.GetRules()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if _, err := position.Index(len(rules)); err != nil {
+		fmt.Println(err)
+		os.Exit(exitInvalidRule)
+	}
+}
+
 var (
 	tablesExact string
 	tablesAny   string
@@ -86,20 +151,95 @@ var (
 	queryIds    string
 	regex       string
 	ttl         string
+	noCache     bool
 	confirmed   bool
+
+	makeruleDryRun    bool
+	makerulePosition  string
+	makeruleFromQuery string
+	makeruleFromTable string
 )
 
+// addRuleFlags adds the flags that set the conditions and TTL of a rule.
+func addRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&tablesExact, "tablesExact", "e", "", "Comma-delimited unordered set of tables. Matches if all of the tables (and no others) appear in the query.")
+	cmd.Flags().StringVarP(&tablesAny, "tablesAny", "x", "", "Comma-delimited unordered set of tables. Matches if any of these tables appear in the query.")
+	cmd.Flags().StringVarP(&tablesAll, "tablesAll", "l", "", "Comma-delimited unordered set of tables. Matches if all of the tables in the set appear in the query.")
+	cmd.Flags().StringVarP(&queryIds, "queryIds", "q", "", "Comma-delimited unordered set of the ids of the Queries that the rule will apply to.")
+	cmd.Flags().StringVarP(&regex, "regex", "r", "", "The regex to use to match this rule. If the regex matches, the rule wil apply")
+	cmd.Flags().StringVarP(&ttl, "ttl", "t", "", "The time to live as a duration (e.g. 5m, 300s, 2d) to enforce as the ttl.")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Disable caching for the queries the rule matches, instead of giving a --ttl.")
+	cmd.MarkFlagsMutuallyExclusive("ttl", "no-cache")
+}
+
+// parseListFlag parses a comma-delimited condition flag, an empty value removes the condition.
+func parseListFlag(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return RuleMatcher.ParseTables(value)
+}
+
+// errMatchAllRule is returned for a rule without conditions, which would match every query.
+var errMatchAllRule = errors.New("a rule needs at least one condition, e.g. --tablesAny or --regex, as it would otherwise match every query")
+
+// applyRuleFlags sets the conditions and TTL of rule that were given as flags, leaving the others as they are. A
+// condition flag with an empty value removes the condition, but the rule must keep at least one.
+func applyRuleFlags(cmd *cobra.Command, rule RedisCommon.Rule) (RedisCommon.Rule, error) {
+	flags := cmd.Flags()
+	if flags.Changed("tablesExact") {
+		rule.Tables = parseListFlag(tablesExact)
+	}
+	if flags.Changed("tablesAny") {
+		rule.TablesAny = parseListFlag(tablesAny)
+	}
+	if flags.Changed("tablesAll") {
+		rule.TablesAll = parseListFlag(tablesAll)
+	}
+	if flags.Changed("queryIds") {
+		rule.QueryIds = parseListFlag(queryIds)
+	}
+
+	if flags.Changed("regex") {
+		rule.Regex = nil
+		if regex != "" {
+			err := RuleMatcher.CheckRegex(regex)
+			if errors.Is(err, RuleMatcher.ErrInvalidRegex) {
+				return rule, err
+			}
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+			r := regex
+			rule.Regex = &r
+		}
+	}
+
+	if noCache {
+		rule.Ttl = RedisCommon.NoCache.String()
+	} else if flags.Changed("ttl") {
+		t, err := RedisCommon.ParseRuleTtl(ttl, false)
+		if err != nil {
+			return rule, err
+		}
+		rule.Ttl = t.String()
+	}
+
+	if rule.GetType() == RedisCommon.All {
+		return rule, errMatchAllRule
+	}
+
+	return rule, nil
+}
+
 func init() {
 	rootCmd.AddCommand(makeruleCmd)
-	makeruleCmd.Flags().StringVarP(&tablesExact, "tablesExact", "e", "", "Comma-delimited unordered set of tables. Matches if all of the tables (and no others) appear in the query.")
-	makeruleCmd.Flags().StringVarP(&tablesAny, "tablesAny", "x", "", "Comma-delimited unordered set of tables. Matches if any of these tables appear in the query.")
-	makeruleCmd.Flags().StringVarP(&tablesAll, "tablesAll", "l", "", "Comma-delimited unordered set of tables. Matches if all of the tables in the set appear in the query.")
-	makeruleCmd.Flags().StringVarP(&queryIds, "queryIds", "q", "", "Comma-delimited unordered set of the ids of the Queries that the rule will apply to.")
-	makeruleCmd.Flags().StringVarP(&regex, "regex", "r", "", "The regex to use to match this rule. If the regex matches, the rule wil apply")
-	makeruleCmd.Flags().StringVarP(&ttl, "ttl", "t", "", "The time to live as a duration (e.g. 5m, 300s, 2d) to enforce as the ttl.")
+	addRuleFlags(makeruleCmd)
 	makeruleCmd.Flags().BoolVarP(&confirmed, "confirm", "y", false, "provide this flag if you don't want the interactive dialog to confirm for you before committing.")
-	err := makeruleCmd.MarkFlagRequired("ttl")
-	if err != nil {
-		panic(err)
-	}
+	makeruleCmd.Flags().BoolVar(&makeruleDryRun, "dry-run", false, "Only show which queries the rule would newly cache or change the TTL of, don't commit it.")
+	makeruleCmd.Flags().StringVar(&makerulePosition, "position", "top", "Where to insert the rule: top, bottom or a precedence starting at 0.")
+	makeruleCmd.Flags().StringVar(&makeruleFromQuery, "from-query", "", "Create the rule for the query with this id, instead of giving --queryIds.")
+	makeruleCmd.Flags().StringVar(&makeruleFromTable, "from-table", "", "Create the rule for the queries that use this table, instead of giving --tablesAny.")
+	makeruleCmd.MarkFlagsMutuallyExclusive("from-query", "queryIds")
+	makeruleCmd.MarkFlagsMutuallyExclusive("from-table", "tablesAny")
 }
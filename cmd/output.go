@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+	jsonlOutput = "jsonl"
+	csvOutput   = "csv"
+	tsvOutput   = "tsv"
+	yamlOutput  = "yaml"
+)
+
+var outputFormats = []string{tableOutput, jsonOutput, jsonlOutput, csvOutput, tsvOutput, yamlOutput}
+
+var (
+	outputFormat   string
+	outputTemplate string
+)
+
+// record is a row of structured output. It is encoded as is in JSON and YAML, and flattened into columns for CSV
+// and TSV.
+type record interface {
+	Columns() []string
+	Values() []string
+}
+
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", tableOutput, "The output format, one of "+strings.Join(outputFormats, ", ")+".")
+	cmd.Flags().StringVar(&outputTemplate, "template", "", "A Go text/template executed for each row, e.g. '{{.Id}} {{.Ttl}}'. Overrides --output.")
+}
+
+// parseOutputFlags checks the output flags, returning the template to render rows with if one was given.
+func parseOutputFlags(cmd *cobra.Command) (*template.Template, error) {
+	if outputTemplate != "" {
+		if cmd.Flags().Changed("output") {
+			return nil, fmt.Errorf("--output and --template cannot be combined")
+		}
+		tmpl, err := template.New("row").Funcs(template.FuncMap{"json": toJson}).Parse(outputTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --template: %w", err)
+		}
+		return tmpl, nil
+	}
+
+	outputFormat = strings.ToLower(outputFormat)
+	for _, f := range outputFormats {
+		if f == outputFormat {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown output format '%s', valid formats are %s", outputFormat, strings.Join(outputFormats, ", "))
+}
+
+func toJson(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// writeRecords writes the records in a machine-readable format, or executes tmpl for each of them when it is set.
+func writeRecords[T record](w io.Writer, format string, tmpl *template.Template, records []T) error {
+	if tmpl != nil {
+		for _, r := range records {
+			if err := tmpl.Execute(w, r); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch format {
+	case jsonOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case jsonlOutput:
+		encoder := json.NewEncoder(w)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yamlOutput:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case csvOutput, tsvOutput:
+		writer := csv.NewWriter(w)
+		if format == tsvOutput {
+			writer.Comma = '\t'
+		}
+		var zero T
+		if err := writer.Write(zero.Columns()); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := writer.Write(r.Values()); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return fmt.Errorf("unknown output format '%s'", format)
+}
+
+// writeRecord writes a single record, as an object rather than a list in JSON and YAML.
+func writeRecord[T record](w io.Writer, format string, tmpl *template.Template, r T) error {
+	switch {
+	case tmpl == nil && format == jsonOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case tmpl == nil && format == yamlOutput:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(r); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return writeRecords(w, format, tmpl, []T{r})
+}
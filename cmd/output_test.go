@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"smart-cache-cli/RedisCommon"
+	"testing"
+	"text/template"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with the golden file of that name in testdata/output, or rewrites the file with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "output", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	rule := RedisCommon.Rule{TablesAny: []string{"orders"}, Ttl: "300s"}
+	noCache := RedisCommon.Rule{Tables: []string{"customers"}, Ttl: "0s"}
+	query := &RedisCommon.Query{
+		Id:        "8c61a5b2",
+		Key:       "smartcache:query:8c61a5b2",
+		Table:     "orders",
+		Sql:       "SELECT id, total FROM orders WHERE id = ?",
+		Count:     42,
+		MeanTime:  1.5,
+		Rule:      &rule,
+		RuleIndex: 0,
+	}
+	table := &RedisCommon.Table{Name: "customers", AccessFrequency: 7, QueryTime: 12.25, Rule: &noCache, RuleIndex: 1}
+
+	for _, format := range []string{jsonOutput, jsonlOutput, yamlOutput, csvOutput, tsvOutput} {
+		t.Run(format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeRecords(&b, format, nil, []RedisCommon.QueryRecord{query.Record()}); err != nil {
+				t.Fatalf("writeRecords() error = %v", err)
+			}
+			checkGolden(t, format+"-query.golden", b.Bytes())
+
+			b.Reset()
+			if err := writeRecords(&b, format, nil, []RedisCommon.TableRecord{table.Record()}); err != nil {
+				t.Fatalf("writeRecords() error = %v", err)
+			}
+			checkGolden(t, format+"-table.golden", b.Bytes())
+		})
+	}
+
+	t.Run("template", func(t *testing.T) {
+		tmpl := template.Must(template.New("row").Funcs(template.FuncMap{"json": toJson}).
+			Parse(`{{.Id}}: {{if eq .Caching "enabled"}}{{.Ttl}}{{else}}not cached{{end}} {{json .Rule}}`))
+		var b bytes.Buffer
+		if err := writeRecords(&b, tableOutput, tmpl, []RedisCommon.QueryRecord{query.Record()}); err != nil {
+			t.Fatalf("writeRecords() error = %v", err)
+		}
+		if want := "8c61a5b2: 5m {\"tablesAny\":[\"orders\"],\"ttl\":\"300s\"}\n"; b.String() != want {
+			t.Errorf("writeRecords() = %q, want %q", b.String(), want)
+		}
+	})
+}
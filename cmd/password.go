@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+var (
+	AskPassword     bool
+	PasswordCommand string
+
+	// passwordResolved is whether resolvePassword found a password in any source, so that it also overrides the
+	// password of --uri.
+	passwordResolved bool
+	// readPassword prompts for the password, replaced in tests.
+	readPassword = promptPassword
+)
+
+// promptPassword reads the password from the terminal without echoing it.
+func promptPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("--ask-password requires an interactive terminal")
+	}
+
+	fmt.Fprint(os.Stderr, "Redis password: ")
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("unable to read password: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// runPasswordCommand runs a credential helper through the shell and returns its output as the password.
+func runPasswordCommand(command string) (string, error) {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Stdin = os.Stdin
+	c.Stderr = os.Stderr
+
+	out, err := c.Output()
+	if err != nil {
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// resolvePassword settles the password used by the shared client options. The password, prompt and password
+// command are taken together from the first source that gives any of them, with the precedence applySettings uses:
+// flags given on the command line, then the environment, then the selected profile. Within a source a password wins
+// over the prompt, which wins over a password command.
+func resolvePassword(flags *pflag.FlagSet, profileSettings map[string]string) error {
+	if flags.Changed("ask-password") && AskPassword && (flags.Changed("password") || flags.Changed("password-command")) {
+		return errors.New("--ask-password cannot be combined with --password or --password-command")
+	}
+
+	fromFlags := func(name string) (string, bool) {
+		if !flags.Changed(name) {
+			return "", false
+		}
+		return flags.Lookup(name).Value.String(), true
+	}
+	fromEnv := func(name string) (string, bool) {
+		return os.LookupEnv(envName(name))
+	}
+	fromProfile := func(name string) (string, bool) {
+		value, ok := profileSettings[name]
+		return value, ok
+	}
+
+	passwordResolved = false
+	for _, lookup := range []func(name string) (string, bool){fromFlags, fromEnv, fromProfile} {
+		if password, ok := lookup("password"); ok {
+			Password = password
+			passwordResolved = true
+			return nil
+		}
+
+		if ask, ok := lookup("ask-password"); ok {
+			if ask, err := strconv.ParseBool(ask); err == nil && ask {
+				var err error
+				Password, err = readPassword()
+				passwordResolved = err == nil
+				return err
+			}
+		}
+
+		if command, ok := lookup("password-command"); ok {
+			var err error
+			PasswordCommand = command
+			Password, err = runPasswordCommand(command)
+			passwordResolved = err == nil
+			return err
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&AskPassword, "ask-password", false, "Prompt for the Redis password without echoing it")
+	rootCmd.PersistentFlags().StringVar(&PasswordCommand, "password-command", "", "Command whose output is used as the Redis password (e.g. 'pass show redis/prod')")
+}
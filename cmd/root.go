@@ -3,11 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"smart-cache-cli/RedisCommon"
 	"smart-cache-cli/mainMenu"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/redis/go-redis/v9"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +14,14 @@ const (
 	version = "0.0.13"
 )
 
+// Exit codes besides 1 for any other error, so that scripts can tell why a command changed nothing.
+const (
+	// exitInvalidRule is returned when the flags do not describe a valid rule or position.
+	exitInvalidRule = 2
+	// exitNotInteractive is returned when a change needs confirming but stdin is not a terminal.
+	exitNotInteractive = 3
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "redis-smartcache-cli",
@@ -26,29 +33,16 @@ query anlytics, create query caching rules, and reset Smart Cache configuration.
 			fmt.Printf("Redis Smart Cache CLI version v%s\n", version)
 			os.Exit(0)
 		}
-		rdb := redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%s", HostName, Port),
-			Password: Password,
-			Username: User,
-			DB:       0,
-			Protocol: 2,
-		})
-
-		err := RedisCommon.Ping(rdb)
-
-		if err != nil {
-			fmt.Printf("Error connecting to Redis: \"%s\".\n", err.Error())
-			os.Exit(1)
-		}
+		store, info := mustOpenStore(cmd)
 
-		err = RedisCommon.CheckSmartCacheIndex(rdb, ApplicationName)
+		err := store.CheckSmartCacheIndex()
 
 		if err != nil {
 			fmt.Printf("Error checking Redis Smart Cache configuration: %s\n", err)
 			os.Exit(1)
 		}
 
-		p := tea.NewProgram(mainMenu.InitialModel(rdb, ApplicationName, fmt.Sprintf("%s:%s", HostName, Port)))
+		p := tea.NewProgram(mainMenu.InitialModel(store, info, refreshInterval))
 		if res, err := p.Run(); err != nil {
 			fmt.Printf("Smart Cache CLI error: %v", err)
 			os.Exit(1)
@@ -71,6 +65,7 @@ var User string
 var Password string
 var ApplicationName string
 var versionCheck bool
+var refreshInterval time.Duration
 var (
 	sortby        string
 	sortDirection string
@@ -83,4 +78,5 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&User, "user", "u", "default", "Redis user")
 	rootCmd.PersistentFlags().StringVarP(&ApplicationName, "application", "s", "smartcache", "Application namespace")
 	rootCmd.Flags().BoolVarP(&versionCheck, "version", "v", false, "Smart Cache CLI version")
+	rootCmd.Flags().DurationVar(&refreshInterval, "refresh-interval", 5*time.Second, "How often the query list reloads the queries, 0 turns auto-refresh off")
 }
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"smart-cache-cli/RedisCommon"
+	"smart-cache-cli/RuleLint"
+
+	"github.com/spf13/cobra"
+)
+
+// rulesCmd groups the commands that manage the caching rules as a whole
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage the caching rules",
+	Long:  `Manage the caching rules, e.g. to keep them in a file under version control next to your application.`,
+}
+
+var rulesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the current caching rules to a YAML or JSON file",
+	Long: `Writes the current caching rules, in order of precedence, to a YAML or JSON file or to stdout.
+The format is taken from --format, or else from the file extension.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+
+		rules, err := store.GetRules()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		format := rulesFormat
+		if format == "" {
+			format = RedisCommon.FormatFromPath(rulesFile)
+		}
+
+		b, err := RedisCommon.MarshalRulesFile(RedisCommon.RulesFile{Application: store.ApplicationName(), Rules: rules}, format)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if rulesFile == "" {
+			fmt.Print(string(b))
+			return
+		}
+
+		err = os.WriteFile(rulesFile, b, 0644)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d rules to %s.\n", len(rules), rulesFile)
+	},
+}
+
+var rulesImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Replace the caching rules with the rules in a file",
+	Long: `Replaces the caching rules with the rules in a YAML or JSON file, publishing them as a single new
+config entry. A file exported for another application is rejected unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+		file := mustReadRulesFile(args[0])
+		mustMatchApplication(file, store)
+		mustValidateRuleTtls(file.Rules)
+
+		current, err := store.GetRuleSet()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		changes := RedisCommon.DiffRules(current.Rules, file.Rules)
+		if !RedisCommon.HasChanges(changes) {
+			fmt.Println("The caching rules already match the file.")
+			return
+		}
+
+		fmt.Print(RedisCommon.FormatRuleDiff(changes))
+		if rulesDryRun {
+			return
+		}
+
+		if !rulesConfirmed && !askYesNo("Do you want to replace the caching rules? (y/N) ") {
+			return
+		}
+
+		_, err = store.ReplaceRules(current.Id, file.Rules)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported %d rules.\n", len(file.Rules))
+	},
+}
+
+var rulesApplyCmd = &cobra.Command{
+	Use:   "apply <file>",
+	Short: "Update the caching rules to match a file",
+	Long: `Computes the rules to add, update, move and delete that make the caching rules match a YAML or JSON file,
+shows the plan and commits it. With --dry-run only the plan is shown. A file exported for another application is
+rejected unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+		file := mustReadRulesFile(args[0])
+		mustMatchApplication(file, store)
+		mustValidateRuleTtls(file.Rules)
+
+		current, err := store.GetRuleSet()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		order := RedisCommon.PlanRuleUpdates(current.Rules, file.Rules)
+		if !RedisCommon.HasRuleChanges(current.Rules, order) {
+			fmt.Println("The caching rules already match the file.")
+			return
+		}
+
+		fmt.Print(RedisCommon.FormatRuleUpdates(current.Rules, order))
+		if rulesDryRun {
+			return
+		}
+
+		if !rulesConfirmed && !askYesNo("Do you want to continue? (y/N) ") {
+			return
+		}
+
+		err = store.UpdateRules(current.Id, order)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Rule Updates Committed to Redis.")
+	},
+}
+
+var rulesLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the caching rules for problems",
+	Long: `Checks the caching rules, or the rules in a file given with --file, for invalid TTLs and regexes,
+empty table lists, duplicate rules, rules shadowed by an earlier rule, and query IDs Smart Cache has not
+seen. Exits with status 1 if any errors, or with --strict any warnings, are found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, _ := mustOpenStore(cmd)
+
+		var rules []RedisCommon.Rule
+		var err error
+		if rulesFile != "" {
+			rules = mustReadRulesFile(rulesFile).Rules
+		} else {
+			rules, err = store.GetRules()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		queries, err := store.GetQueries()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		findings := RuleLint.Lint(rules, queries)
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+
+		if len(findings) == 0 {
+			fmt.Printf("No problems found in %d rules.\n", len(rules))
+		}
+
+		if RuleLint.HasErrors(findings) || (rulesLintStrict && len(findings) > 0) {
+			os.Exit(1)
+		}
+	},
+}
+
+func mustReadRulesFile(path string) RedisCommon.RulesFile {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	format := rulesFormat
+	if format == "" {
+		format = RedisCommon.FormatFromPath(path)
+	}
+
+	file, err := RedisCommon.UnmarshalRulesFile(b, format)
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	return file
+}
+
+// mustMatchApplication exits the CLI if the rules file names an application other than the one whose rules it would
+// replace, unless --force is given.
+func mustMatchApplication(file RedisCommon.RulesFile, store RedisCommon.SmartCacheStore) {
+	if rulesForce || file.Application == "" || file.Application == store.ApplicationName() {
+		return
+	}
+
+	fmt.Printf("The rules file is for application '%s' but the rules of application '%s' would be replaced, provide --force to use it anyway.\n",
+		file.Application, store.ApplicationName())
+	os.Exit(1)
+}
+
+// mustValidateRuleTtls exits the CLI if a rule has a TTL Smart Cache cannot parse or outside of the TTL policy. Rules
+// in a file may disable caching with a TTL of zero.
+func mustValidateRuleTtls(rules []RedisCommon.Rule) {
+	for i, r := range rules {
+		if err := RedisCommon.ValidateTtl(r.Ttl, true); err != nil {
+			fmt.Printf("rule %d: %v\n", i, err)
+			os.Exit(1)
+		}
+	}
+}
+
+var (
+	rulesFile       string
+	rulesFormat     string
+	rulesDryRun     bool
+	rulesConfirmed  bool
+	rulesForce      bool
+	rulesLintStrict bool
+)
+
+func init() {
+	rulesCmd.PersistentFlags().StringVar(&rulesFormat, "format", "", "The rules file format, 'yaml' or 'json'. Defaults to the format of the file extension, or YAML.")
+
+	rulesExportCmd.Flags().StringVarP(&rulesFile, "file", "f", "", "The file to write the rules to instead of stdout.")
+	rulesLintCmd.Flags().StringVarP(&rulesFile, "file", "f", "", "The rules file to check instead of the current rules.")
+	rulesLintCmd.Flags().BoolVar(&rulesLintStrict, "strict", false, "Exit with status 1 on warnings as well as errors.")
+
+	for _, c := range []*cobra.Command{rulesImportCmd, rulesApplyCmd} {
+		c.Flags().BoolVar(&rulesDryRun, "dry-run", false, "Only show the changes, don't commit them.")
+		c.Flags().BoolVarP(&rulesConfirmed, "confirm", "y", false, "provide this flag if you don't want to be asked for confirmation before committing.")
+		c.Flags().BoolVar(&rulesForce, "force", false, "Use a rules file exported for another application.")
+	}
+
+	rulesCmd.AddCommand(rulesExportCmd)
+	rulesCmd.AddCommand(rulesImportCmd)
+	rulesCmd.AddCommand(rulesApplyCmd)
+	rulesCmd.AddCommand(rulesLintCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"smart-cache-cli/RedisCommon"
+	"strconv"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the caching rules in order of precedence",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl := mustParseOutputFlags(cmd)
+		store, _ := mustOpenStore(cmd)
+
+		rules, err := store.GetRules()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		mustWriteRules(tmpl, rules)
+	},
+}
+
+var rulesShowCmd = &cobra.Command{
+	Use:   "show <n>",
+	Short: "Show the caching rule with precedence n",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl := mustParseOutputFlags(cmd)
+		store, _ := mustOpenStore(cmd)
+
+		rules, err := store.GetRules()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		i := mustParseRuleIndex(args[0], rules)
+		if tmpl == nil && outputFormat == tableOutput {
+			fmt.Printf("Rule Precedence:%d\n%s", i, rules[i].Formatted())
+			return
+		}
+
+		err = writeRecord(os.Stdout, outputFormat, tmpl, rules[i].Record(i))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var rulesUpdateCmd = &cobra.Command{
+	Use:   "update <n>",
+	Short: "Update the conditions or TTL of the caching rule with precedence n",
+	Long: `Updates the caching rule with precedence n. Only the conditions given as flags change, a condition flag
+with an empty value, e.g. --regex "", removes the condition.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl := mustParseOutputFlags(cmd)
+		store, _ := mustOpenStore(cmd)
+		current := mustGetRuleSet(store)
+
+		i := mustParseRuleIndex(args[0], current.Rules)
+		rule, err := applyRuleFlags(cmd, current.Rules[i])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(exitInvalidRule)
+		}
+
+		order := RedisCommon.BaseOrder(current.Rules)
+		order[i].Rule = rule
+		commitRuleOrder(store, current, order, tmpl)
+	},
+}
+
+var rulesDeleteCmd = &cobra.Command{
+	Use:   "delete <n>...",
+	Short: "Delete the caching rules with the given precedences",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl := mustParseOutputFlags(cmd)
+		store, _ := mustOpenStore(cmd)
+		current := mustGetRuleSet(store)
+
+		deleted := make(map[int]bool)
+		for _, arg := range args {
+			deleted[mustParseRuleIndex(arg, current.Rules)] = true
+		}
+
+		order := make([]RedisCommon.OrderedRule, 0, len(current.Rules))
+		for _, o := range RedisCommon.BaseOrder(current.Rules) {
+			if !deleted[o.BaseIndex] {
+				order = append(order, o)
+			}
+		}
+		commitRuleOrder(store, current, order, tmpl)
+	},
+}
+
+var rulesMoveCmd = &cobra.Command{
+	Use:   "move <n> --to <m>",
+	Short: "Move the caching rule with precedence n to precedence m",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl := mustParseOutputFlags(cmd)
+		store, _ := mustOpenStore(cmd)
+		current := mustGetRuleSet(store)
+
+		from := mustParseRuleIndex(args[0], current.Rules)
+		to := mustParseRuleIndex(strconv.Itoa(rulesMoveTo), current.Rules)
+
+		order := RedisCommon.BaseOrder(current.Rules)
+		moved := order[from]
+		order = append(order[:from], order[from+1:]...)
+		order = append(order[:to], append([]RedisCommon.OrderedRule{moved}, order[to:]...)...)
+		commitRuleOrder(store, current, order, tmpl)
+	},
+}
+
+var rulesClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete all caching rules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tmpl := mustParseOutputFlags(cmd)
+		store, _ := mustOpenStore(cmd)
+		current := mustGetRuleSet(store)
+
+		commitRuleOrder(store, current, []RedisCommon.OrderedRule{}, tmpl)
+	},
+}
+
+func mustParseOutputFlags(cmd *cobra.Command) *template.Template {
+	tmpl, err := parseOutputFlags(cmd)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return tmpl
+}
+
+func mustGetRuleSet(store RedisCommon.SmartCacheStore) RedisCommon.RuleSet {
+	ruleSet, err := store.GetRuleSet()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return ruleSet
+}
+
+// mustParseRuleIndex parses a rule precedence given on the command line, which starts at 0 like the Rule Precedence
+// column of the rule list.
+func mustParseRuleIndex(arg string, rules []RedisCommon.Rule) int {
+	i, err := strconv.Atoi(arg)
+	if err != nil {
+		fmt.Printf("'%s' is not a rule precedence, expected a number.\n", arg)
+		os.Exit(1)
+	}
+
+	if i < 0 || i >= len(rules) {
+		if len(rules) == 0 {
+			fmt.Printf("Rule %d does not exist, there are no rules.\n", i)
+		} else {
+			fmt.Printf("Rule %d does not exist, rules are numbered 0 to %d.\n", i, len(rules)-1)
+		}
+		os.Exit(1)
+	}
+	return i
+}
+
+// mustWriteRules lists the rules in the output format, one line per rule for the table format.
+func mustWriteRules(tmpl *template.Template, rules []RedisCommon.Rule) {
+	if tmpl == nil && outputFormat == tableOutput {
+		if len(rules) == 0 {
+			fmt.Println("There are no caching rules.")
+		}
+		for i, r := range rules {
+			fmt.Printf("%3d. %s\n", i, r.Summary())
+		}
+		return
+	}
+
+	records := make([]RedisCommon.RuleRecord, len(rules))
+	for i, r := range rules {
+		records[i] = r.Record(i)
+	}
+	err := writeRecords(os.Stdout, outputFormat, tmpl, records)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// commitRuleOrder shows the changes order makes to the current rules and their impact, asks for confirmation unless
+// --confirm was given, and commits them with UpdateRules. With an output format other than table, the changes are
+// not shown and the resulting rules are written in that format instead.
+func commitRuleOrder(store RedisCommon.SmartCacheStore, current RedisCommon.RuleSet, order []RedisCommon.OrderedRule, tmpl *template.Template) {
+	structured := tmpl != nil || outputFormat != tableOutput
+	if !RedisCommon.HasRuleChanges(current.Rules, order) {
+		if structured {
+			mustWriteRules(tmpl, current.Rules)
+		} else {
+			fmt.Println("The caching rules are unchanged.")
+		}
+		return
+	}
+
+	impact, err := RedisCommon.PreviewRuleUpdates(store, current.Rules, order)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// With structured output the plan is left out of stdout, but it is still shown on stderr before asking for
+	// confirmation so that the change being confirmed can be seen.
+	plan := os.Stdout
+	if structured {
+		plan = os.Stderr
+	}
+	if !structured || (!rulesDryRun && !rulesConfirmed) {
+		fmt.Fprint(plan, RedisCommon.FormatRuleUpdates(current.Rules, order))
+		fmt.Fprint(plan, "\nImpact on queries:\n"+impact.Formatted())
+	}
+
+	if rulesDryRun {
+		if structured {
+			mustWriteRules(tmpl, orderedRules(order))
+		}
+		return
+	}
+
+	if !rulesConfirmed && !askYesNo("Do you want to continue? (y/N) ") {
+		return
+	}
+
+	err = store.UpdateRules(current.Id, order)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if structured {
+		mustWriteRules(tmpl, orderedRules(order))
+	} else {
+		fmt.Println("Rule Updates Committed to Redis.")
+	}
+}
+
+func orderedRules(order []RedisCommon.OrderedRule) []RedisCommon.Rule {
+	rules := make([]RedisCommon.Rule, len(order))
+	for i, o := range order {
+		rules[i] = o.Rule
+	}
+	return rules
+}
+
+var rulesMoveTo int
+
+func init() {
+	addRuleFlags(rulesUpdateCmd)
+	rulesMoveCmd.Flags().IntVar(&rulesMoveTo, "to", 0, "The precedence to move the rule to, 0 is the highest.")
+	err := rulesMoveCmd.MarkFlagRequired("to")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, c := range []*cobra.Command{rulesListCmd, rulesShowCmd, rulesUpdateCmd, rulesDeleteCmd, rulesMoveCmd, rulesClearCmd} {
+		addOutputFlags(c)
+		rulesCmd.AddCommand(c)
+	}
+
+	for _, c := range []*cobra.Command{rulesUpdateCmd, rulesDeleteCmd, rulesMoveCmd, rulesClearCmd} {
+		c.Flags().BoolVar(&rulesDryRun, "dry-run", false, "Only show the changes, don't commit them.")
+		c.Flags().BoolVarP(&rulesConfirmed, "confirm", "y", false, "provide this flag if you don't want to be asked for confirmation before committing.")
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	TlsEnabled    bool
+	TlsCaCert     string
+	TlsCert       string
+	TlsKey        string
+	TlsServerName string
+	TlsInsecure   bool
+)
+
+// useTls reports whether any of the TLS flags were provided, supplying a CA bundle, client certificate
+// or server name implies --tls.
+func useTls() bool {
+	return TlsEnabled || TlsCaCert != "" || TlsCert != "" || TlsKey != "" || TlsServerName != "" || TlsInsecure
+}
+
+// applyTlsConfig sets up the TLS configuration of the connection options from the TLS flags. A rediss:// URI
+// will already have enabled TLS on the options, in which case the flags are layered on top of it.
+func applyTlsConfig(opts *redis.Options) error {
+	if !useTls() && opts.TLSConfig == nil {
+		return nil
+	}
+
+	if (TlsCert == "") != (TlsKey == "") {
+		return errors.New("both --cert and --key must be provided to use mutual TLS")
+	}
+
+	tlsConfig := opts.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	tlsConfig.InsecureSkipVerify = TlsInsecure
+	switch {
+	case TlsServerName != "":
+		tlsConfig.ServerName = TlsServerName
+	case Cluster || SentinelMaster != "":
+		// Left unset, each node is verified against the host it is dialed at rather than the seed host.
+		tlsConfig.ServerName = ""
+	case tlsConfig.ServerName == "":
+		host, _, err := net.SplitHostPort(opts.Addr)
+		if err != nil {
+			host = opts.Addr
+		}
+		tlsConfig.ServerName = host
+	}
+
+	if TlsCaCert != "" {
+		pem, err := os.ReadFile(TlsCaCert)
+		if err != nil {
+			return fmt.Errorf("unable to read CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates found in CA bundle '%s'", TlsCaCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if TlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(TlsCert, TlsKey)
+		if err != nil {
+			return fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	opts.TLSConfig = tlsConfig
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&TlsEnabled, "tls", false, "Connect to Redis using TLS")
+	rootCmd.PersistentFlags().StringVar(&TlsCaCert, "cacert", "", "CA certificate bundle (PEM) used to verify the Redis server, implies --tls")
+	rootCmd.PersistentFlags().StringVar(&TlsCert, "cert", "", "Client certificate (PEM) for mutual TLS, implies --tls")
+	rootCmd.PersistentFlags().StringVar(&TlsKey, "key", "", "Client private key (PEM) for mutual TLS, implies --tls")
+	rootCmd.PersistentFlags().StringVar(&TlsServerName, "sni", "", "Server name used to verify the Redis server certificate, defaults to the host, implies --tls")
+	rootCmd.PersistentFlags().BoolVar(&TlsInsecure, "insecure", false, "Skip verification of the Redis server certificate, implies --tls")
+}
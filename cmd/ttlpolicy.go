@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"smart-cache-cli/RedisCommon"
+)
+
+var (
+	MinTtl string
+	MaxTtl string
+)
+
+// parseTtlBound parses the value of a TTL bound flag, an empty value leaves the bound unenforced.
+func parseTtlBound(flagName string, value string) (RedisCommon.Ttl, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	ttl, err := RedisCommon.ParseTtl(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %w", flagName, err)
+	}
+	return ttl, nil
+}
+
+// applyTtlPolicy sets the TTL policy that rules are validated against from --min-ttl and --max-ttl.
+func applyTtlPolicy() error {
+	minTtl, err := parseTtlBound("min-ttl", MinTtl)
+	if err != nil {
+		return err
+	}
+
+	maxTtl, err := parseTtlBound("max-ttl", MaxTtl)
+	if err != nil {
+		return err
+	}
+
+	if minTtl > 0 && maxTtl > 0 && minTtl > maxTtl {
+		return fmt.Errorf("--min-ttl %s is longer than --max-ttl %s", minTtl, maxTtl)
+	}
+
+	RedisCommon.SetTtlPolicy(RedisCommon.TtlPolicy{Min: minTtl, Max: maxTtl})
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&MinTtl, "min-ttl", "", "Shortest TTL a rule may have (e.g. 10s), rules that disable caching are exempt")
+	rootCmd.PersistentFlags().StringVar(&MaxTtl, "max-ttl", "", "Longest TTL a rule may have (e.g. 7d)")
+}
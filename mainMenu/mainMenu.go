@@ -6,27 +6,29 @@ import (
 	"smart-cache-cli/ConfirmationDialog"
 	"smart-cache-cli/RedisCommon"
 	"smart-cache-cli/RuleDialog"
+	"smart-cache-cli/RuleHistory"
 	"smart-cache-cli/RuleList"
 	"smart-cache-cli/TableList"
 	"smart-cache-cli/queryList"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/redis/go-redis/v9"
 )
 
 const listHeight = 14
 
 type Model struct {
-	list            list.Model
-	message         string
-	Choice          string
-	quitting        bool
-	rdb             *redis.Client
-	applicationName string
-	width           int
-	connectionInfo  string
+	list           list.Model
+	message        string
+	Choice         string
+	quitting       bool
+	store          RedisCommon.SmartCacheStore
+	width          int
+	connectionInfo string
+	// refreshInterval is how often the query list reloads the queries.
+	refreshInterval time.Duration
 }
 
 var banner = `
@@ -98,13 +100,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if ok {
 				m.Choice = string(i)
 				if string(i) == listQueries {
-					return queryList.InitialModel(m, m.rdb, m.applicationName, m.width), nil
+					ql := queryList.InitialModel(m, m.store, m.width, m.refreshInterval)
+					return ql, ql.Init()
 				} else if string(i) == createRule {
-					return RuleDialog.New(m, m.rdb, nil, true, m.applicationName, RedisCommon.Unknown), nil
+					return RuleDialog.New(m, m.store, nil, true), nil
 				} else if string(i) == listRules {
-					return RuleList.New(m, m.rdb, m.applicationName), nil
+					return RuleList.New(m, m.store), nil
 				} else if string(i) == listTables {
-					return TableList.New(m, m.rdb, m.applicationName), nil
+					return TableList.New(m, m.store), nil
+				} else if string(i) == ruleHistory {
+					return RuleHistory.New(m, m.store), nil
 				}
 			}
 			return m, tea.Quit
@@ -123,7 +128,7 @@ func (m Model) View() string {
 	if m.quitting {
 		return quitTextStyle.Render("Exiting. . .")
 	}
-	return "\n" + banner + "\n" + fmt.Sprintf("Connected to Redis at '%s' for application keyspace '%s'.\n\n", m.connectionInfo, m.applicationName) + m.list.View() + "\n" + m.message
+	return "\n" + banner + "\n" + fmt.Sprintf("Connected to Redis at '%s' for application keyspace '%s'.\n\n", m.connectionInfo, m.store.ApplicationName()) + m.list.View() + "\n" + m.message
 }
 
 const (
@@ -131,14 +136,16 @@ const (
 	listTables  = "List tables"
 	listRules   = "List query caching rules"
 	createRule  = "Create query caching rule"
+	ruleHistory = "Browse rule history"
 )
 
-func InitialModel(rdb *redis.Client, applicationName string, connectionInfo string) Model {
+func InitialModel(store RedisCommon.SmartCacheStore, connectionInfo string, refreshInterval time.Duration) Model {
 	items := []list.Item{
 		item(listQueries),
 		item(listTables),
 		item(listRules),
 		item(createRule),
+		item(ruleHistory),
 	}
 
 	const defaultWidth = 20
@@ -150,5 +157,5 @@ func InitialModel(rdb *redis.Client, applicationName string, connectionInfo stri
 	l.Styles.Title = titleStyle
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
-	return Model{list: l, rdb: rdb, applicationName: applicationName, connectionInfo: connectionInfo}
+	return Model{list: l, store: store, connectionInfo: connectionInfo, refreshInterval: refreshInterval}
 }
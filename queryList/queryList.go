@@ -3,33 +3,56 @@ package queryList
 import (
 	"fmt"
 	"smart-cache-cli/ConfirmationDialog"
+	"smart-cache-cli/ExplainView"
 	"smart-cache-cli/RedisCommon"
 	"smart-cache-cli/SortDialog"
 	"smart-cache-cli/queryTtlView"
+	"smart-cache-cli/util"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/evertras/bubble-table/table"
-	"github.com/redis/go-redis/v9"
 )
 
 const listHeight = 14
 
 type Model struct {
-	parentModel     tea.Model
-	table           table.Model
-	Queries         []*RedisCommon.Query
-	pendingRules    map[string]RedisCommon.Rule
-	Selection       int
-	rdb             *redis.Client
-	committed       bool
-	sortColumn      string
-	sortDirection   SortDialog.Direction
-	applicationName string
-	width           int
+	parentModel   tea.Model
+	table         table.Model
+	Queries       []*RedisCommon.Query
+	pendingRules  map[string]RedisCommon.Rule
+	Selection     int
+	store         RedisCommon.SmartCacheStore
+	committed     bool
+	sortColumn    string
+	sortDirection SortDialog.Direction
+	width         int
+	err           error
+
+	// refreshInterval is how often the queries are reloaded, zero turns auto-refresh off.
+	refreshInterval time.Duration
+	refreshPaused   bool
+	// refreshId is the id of the running refresh loop. Ticks and loads of any other loop are dropped.
+	refreshId   int
+	lastRefresh time.Time
 }
 
+// refreshMsg is the tick of the auto-refresh loop with the given id.
+type refreshMsg struct {
+	id int
+}
+
+// queriesLoadedMsg carries the queries loaded by the refresh loop with the given id.
+type queriesLoadedMsg struct {
+	id      int
+	queries []*RedisCommon.Query
+	err     error
+}
+
+var changedRowStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+
 var (
 	customBorder = table.Border{
 		Top:    "─",
@@ -53,7 +76,46 @@ var (
 )
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	return m.refreshTick()
+}
+
+// refreshTick schedules the next refresh, unless auto-refresh is off or paused.
+func (m Model) refreshTick() tea.Cmd {
+	if m.refreshInterval <= 0 || m.refreshPaused {
+		return nil
+	}
+
+	id := m.refreshId
+	return tea.Tick(m.refreshInterval, func(time.Time) tea.Msg {
+		return refreshMsg{id: id}
+	})
+}
+
+// loadQueries loads the queries in the background for the refresh loop.
+func (m Model) loadQueries() tea.Cmd {
+	id, store := m.refreshId, m.store
+	return func() tea.Msg {
+		queries, err := store.GetQueries()
+		return queriesLoadedMsg{id: id, queries: queries, err: err}
+	}
+}
+
+// detach returns the model to show again once a view opened over it closes. Its refresh loop ends, as its ticks go
+// to that view, and a new one starts from Init or from the message the view closes with.
+func (m Model) detach() Model {
+	m.refreshId++
+	return m
+}
+
+func (m Model) refreshStatus() string {
+	switch {
+	case m.refreshInterval <= 0:
+		return "Auto-refresh: off"
+	case m.refreshPaused:
+		return "Auto-refresh: paused"
+	default:
+		return fmt.Sprintf("Auto-refresh: every %s, last at %s", m.refreshInterval, m.lastRefresh.Format("15:04:05"))
+	}
 }
 
 func (m Model) updateFooter() table.Model {
@@ -63,11 +125,12 @@ func (m Model) updateFooter() table.Model {
 		successfullyCommittedText = "Successfuly commited caching rules!           "
 	}
 	footerText := fmt.Sprintf(
-		"%sPg. %d/%d - Pending Updates: %d",
+		"%sPg. %d/%d - Pending Updates: %d - %s",
 		successfullyCommittedText,
 		m.table.CurrentPage(),
 		m.table.MaxPages(),
 		len(m.pendingRules),
+		m.refreshStatus(),
 	)
 
 	return m.table.WithStaticFooter(footerText)
@@ -95,45 +158,78 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlC.String(), "q":
 			return m.parentModel, tea.Quit
 		case tea.KeyTab.String(), tea.KeySpace.String(), tea.KeyEnter.String():
+			if len(m.Queries) == 0 {
+				return m, cmd
+			}
 			m.Selection = m.table.HighlightedRow().Data["RowId"].(int)
 			//m.EditMode = !m.EditMode
-			return queryTtlView.New(m.Queries[m.Selection], m, m.width), cmd
+			return queryTtlView.New(m.Queries[m.Selection], m.detach(), m.store, m.width), cmd
+		case "e":
+			if len(m.Queries) == 0 {
+				return m, cmd
+			}
+			m.Selection = m.table.HighlightedRow().Data["RowId"].(int)
+			return ExplainView.New(m.Queries[m.Selection], m.store, m.detach(), m.width), cmd
 		case "i":
 			m.table = m.table.WithHeaderVisibility(!m.table.GetHeaderVisibility())
 		case "c":
-			return ConfirmationDialog.New(m, m.pendingRules), cmd
+			return ConfirmationDialog.New(m.detach(), m.pendingRules, m.store), cmd
 		case "s":
-			return SortDialog.New(RedisCommon.GetColumnNames(), m), nil
+			return SortDialog.New(RedisCommon.GetColumnNames(), m.detach()), nil
+		case "p":
+			m.refreshPaused = !m.refreshPaused
+			m.refreshId++
+			m.table = m.updateFooter()
+			return m, tea.Batch(cmd, m.refreshTick())
 		case tea.KeyEsc.String(), "b":
 			m.parentModel, _ = m.parentModel.Update(ConfirmationDialog.ConfirmationMessage{ConfirmedUpdate: true})
 			return m.parentModel, nil
 		}
+	case refreshMsg:
+		if msg.id != m.refreshId {
+			return m, cmd
+		}
+		return m, tea.Batch(cmd, m.loadQueries())
+	case queriesLoadedMsg:
+		if msg.id != m.refreshId {
+			return m, cmd
+		}
+		m.applyQueries(msg.queries, msg.err)
+		return m, tea.Batch(cmd, m.refreshTick())
 	case queryTtlView.SetPendingTtlMsg:
-		m.table.HighlightedRow().Data["Pending Rule"] = msg.Ttl
-		r, ok := m.pendingRules[msg.Ttl]
+		ttl := msg.Ttl.String()
+		m.table.HighlightedRow().Data["Pending Rule"] = ttl
+		m.table.HighlightedRow().Data["Pending Rule Duration"] = msg.Ttl.Duration().Milliseconds()
+		r, ok := m.pendingRules[ttl]
 		if ok {
 			r.QueryIds = append(r.QueryIds, m.Queries[m.Selection].Id)
-			m.pendingRules[msg.Ttl] = r
+			m.pendingRules[ttl] = r
 		} else {
-			m.pendingRules[msg.Ttl] = RedisCommon.Rule{
-				Ttl:      msg.Ttl,
+			m.pendingRules[ttl] = RedisCommon.Rule{
+				Ttl:      ttl,
 				QueryIds: []string{m.Queries[m.Selection].Id},
 			}
 		}
+		return m, tea.Batch(cmd, m.refreshTick())
 	case SortDialog.SortMessage:
 		columns := RedisCommon.GetColumnsOfQuery(msg.Choice, msg.Direction)
 		if msg.Direction == SortDialog.Descending {
-			m.table = m.table.WithColumns(columns).SortByDesc(msg.Choice)
+			m.table = m.table.WithColumns(columns).SortByDesc(RedisCommon.SortKey(msg.Choice))
 		} else {
-			m.table = m.table.WithColumns(columns).SortByAsc(msg.Choice)
+			m.table = m.table.WithColumns(columns).SortByAsc(RedisCommon.SortKey(msg.Choice))
 		}
+		return m, tea.Batch(cmd, m.refreshTick())
 	case ConfirmationDialog.ConfirmationMessage:
 		if msg.ConfirmedUpdate {
 			err := m.CommitRuleUpdate()
 			if err == nil {
-				ResetModel(&m)
+				m.pendingRules = make(map[string]RedisCommon.Rule)
 				m.committed = true
+				// the loaded queries restart the refresh loop
+				return m, tea.Batch(cmd, m.loadQueries())
 			}
+			m.err = err
+			return m, tea.Batch(cmd, m.refreshTick())
 		}
 
 		return m, cmd
@@ -142,20 +238,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func ResetModel(m *Model) {
-	queries, err := RedisCommon.GetQueries(m.rdb, m.applicationName)
-
+// applyQueries shows reloaded queries, keeping the highlighted query, the sort order and the pending rules. Queries
+// that changed since the last load are highlighted and show how much.
+func (m *Model) applyQueries(queries []*RedisCommon.Query, err error) {
+	m.err = err
 	if err != nil {
-		println(err)
+		return
 	}
 
+	pending := make(map[string]RedisCommon.Rule)
+	for _, r := range m.pendingRules {
+		for _, id := range r.QueryIds {
+			pending[id] = r
+		}
+	}
+
+	highlighted := m.table.HighlightedRow().Data["Id"]
+	deltas := RedisCommon.QueryDeltas(m.Queries, queries)
 	rows := make([]table.Row, len(queries))
 	for i, q := range queries {
-		rows[i] = q.GetAsRow(i)
+		if r, ok := pending[q.Id]; ok {
+			q.PendingRule = &r
+		}
+
+		delta := deltas[q.Id]
+		rows[i] = q.GetAsRowWithDelta(i, delta)
+		if delta.Changed() {
+			rows[i] = rows[i].WithStyle(changedRowStyle)
+		}
 	}
 
+	m.Queries = queries
 	m.table = m.table.WithRows(rows)
-	m.pendingRules = make(map[string]RedisCommon.Rule)
+	for i, row := range m.table.GetVisibleRows() {
+		if highlighted != nil && row.Data["Id"] == highlighted {
+			m.table = m.table.WithHighlightedRow(i)
+			break
+		}
+	}
+	m.lastRefresh = time.Now()
+	m.table = m.updateFooter()
 }
 
 func (m Model) CommitRuleUpdate() error {
@@ -164,7 +286,7 @@ func (m Model) CommitRuleUpdate() error {
 		rulesToCommit = append(rulesToCommit, rule)
 	}
 
-	_, err := RedisCommon.CommitNewRules(m.rdb, rulesToCommit, m.applicationName)
+	_, err := m.store.CommitNewRules(rulesToCommit, RedisCommon.TopPosition)
 	return err
 }
 
@@ -176,24 +298,27 @@ func (m Model) View() string {
 	body.WriteString("Press 'i' to toggle the header visibility\n")
 	body.WriteString("Press 's' to change sorting\n")
 	body.WriteString("Press [ENTER] to create a pending rule\n")
+	body.WriteString("Press 'e' to explain which rule applies to a query\n")
 	body.WriteString("Press 'c' to commit selected rules\n")
+	if m.refreshInterval > 0 {
+		body.WriteString("Press 'p' to pause or resume auto-refresh\n")
+	}
 	body.WriteString("Press 'b' to go back\n")
 	body.WriteString("Press [CTRL+C] to quit\n\n")
 
 	body.WriteString(m.table.View())
+	body.WriteString(util.StatusLine(m.err))
 
 	body.WriteString("\n\n")
 
 	return body.String()
 }
 
-func InitialModel(pm tea.Model, rdb *redis.Client, applicationName string, width int) Model {
-
-	queries, err := RedisCommon.GetQueries(rdb, applicationName)
+// InitialModel lists the queries, reloading them every refreshInterval unless it is zero. Return its Init command
+// to start the auto-refresh.
+func InitialModel(pm tea.Model, store RedisCommon.SmartCacheStore, width int, refreshInterval time.Duration) Model {
 
-	if err != nil {
-		println(err)
-	}
+	queries, err := store.GetQueries()
 
 	rows := make([]table.Row, len(queries))
 	for i, q := range queries {
@@ -207,12 +332,15 @@ func InitialModel(pm tea.Model, rdb *redis.Client, applicationName string, width
 			Border(customBorder).
 			WithPageSize(5).
 			SortByDesc("Mean Query Time").WithTargetWidth(200),
-		Queries:         queries,
-		parentModel:     pm,
-		pendingRules:    make(map[string]RedisCommon.Rule),
-		rdb:             rdb,
-		applicationName: applicationName,
-		width:           width,
+		Queries:      queries,
+		parentModel:  pm,
+		pendingRules: make(map[string]RedisCommon.Rule),
+		store:        store,
+		width:        width,
+		err:          err,
+
+		refreshInterval: refreshInterval,
+		lastRefresh:     time.Now(),
 	}
 	model.table = model.updateFooter()
 
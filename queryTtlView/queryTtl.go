@@ -2,8 +2,8 @@ package queryTtlView
 
 import (
 	"fmt"
+	"smart-cache-cli/ExplainView"
 	"smart-cache-cli/RedisCommon"
-	"smart-cache-cli/util"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,7 +14,7 @@ func (m Model) Init() tea.Cmd {
 }
 
 type SetPendingTtlMsg struct {
-	Ttl string
+	Ttl RedisCommon.Ttl
 }
 
 type Model struct {
@@ -22,6 +22,7 @@ type Model struct {
 	query       *RedisCommon.Query
 	pendingTtl  string
 	parentModel *tea.Model
+	store       RedisCommon.SmartCacheStore
 	err         string
 	width       int
 }
@@ -36,16 +37,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyCtrlB, tea.KeyEsc:
-			return *m.parentModel, cmd
+			return *m.parentModel, (*m.parentModel).Init()
 		case tea.KeyCtrlC:
 			*m.parentModel, _ = (*m.parentModel).Update(msg)
 			return *m.parentModel, tea.Quit
+		case tea.KeyCtrlE:
+			return ExplainView.New(m.query, m.store, m, m.width), cmd
 		case tea.KeyEnter:
-			err := util.ValidateTimeout(m.textInput.Value())
+			ttl, err := RedisCommon.ParseRuleTtl(m.textInput.Value(), false)
 			if err != nil {
 				m.err = "\n" + err.Error()
 			} else {
-				(*m.parentModel).Update(SetPendingTtlMsg{Ttl: m.textInput.Value()})
+				*m.parentModel, cmd = (*m.parentModel).Update(SetPendingTtlMsg{Ttl: ttl})
 				return *m.parentModel, cmd
 			}
 		}
@@ -57,10 +60,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m Model) View() string {
 
-	return fmt.Sprintf("%s\n\nPress ctrl+b or escape to return to the previous screen.\nEnter TTL in the form of a duration (e.g. 1h, 300s, 5m):\n%s%s", m.query.Formatted(m.width), m.textInput.View(), m.err)
+	return fmt.Sprintf("%s\n\nPress ctrl+b or escape to return to the previous screen.\nPress ctrl+e to explain which rule applies to this query.\nEnter TTL in the form of a duration (e.g. 1h, 300s, 5m):\n%s%s", m.query.Formatted(m.width), m.textInput.View(), m.err)
 }
 
-func New(query *RedisCommon.Query, pm tea.Model, width int) Model {
+func New(query *RedisCommon.Query, pm tea.Model, store RedisCommon.SmartCacheStore, width int) Model {
 	ti := textinput.New()
 	ti.Placeholder = "30m"
 	ti.Focus()
@@ -72,6 +75,7 @@ func New(query *RedisCommon.Query, pm tea.Model, width int) Model {
 		pendingTtl:  "",
 		parentModel: &pm,
 		query:       query,
+		store:       store,
 		width:       width,
 	}
 }
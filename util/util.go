@@ -1,11 +1,21 @@
 package util
 
 import (
-	"errors"
-	"regexp"
 	"strings"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
+var errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+
+// StatusLine renders an error as the status line at the bottom of a view, or nothing when err is nil.
+func StatusLine(err error) string {
+	if err == nil {
+		return ""
+	}
+	return "\n" + errorStyle.Render("Error: "+err.Error()) + "\n"
+}
+
 func CenterString(str string, width int) string {
 	if len(str) > width {
 		return str[0:width-3] + "..."
@@ -34,20 +44,3 @@ func CompareSlices(slice []string, other []string) bool {
 
 	return true
 }
-
-func ValidateTimeout(input string) error {
-	pattern := "^\\s*(\\d+(?:\\.\\d+)?)\\s*([a-zA-Z]+)\\s*$"
-	matched, err := regexp.MatchString(pattern, input)
-	if err != nil {
-		return err
-	}
-
-	if !matched {
-
-		if !matched {
-			return errors.New("Duration did not match pattern [Number][Duration] (e.g., 300s, 5m, 1h).")
-		}
-
-	}
-	return nil
-}